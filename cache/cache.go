@@ -0,0 +1,275 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/argoproj/pkg/v2/digest"
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// ErrNotFound is returned by Get when the content is not cached
+var ErrNotFound = errors.New("content not found in cache")
+
+// FillFunc writes the content to be cached, e.g. by downloading it
+type FillFunc func(ctx context.Context, w io.Writer) error
+
+// Cache stores files on local disk keyed by the digest of their content. The least recently used
+// files are evicted once the total size exceeds the limit. Content is verified against its digest
+// whenever it is added and read, so a corrupted file is never returned. A Cache is safe for
+// concurrent use, but not by several processes sharing the same directory.
+//
+// Eviction removes files from disk, so callers should open the returned paths right away. Files
+// which are open remain readable after eviction on Unix.
+type Cache struct {
+	dir     string
+	maxSize int64
+	now     func() time.Time
+
+	lock     sync.Mutex
+	entries  map[digest.Digest]*entry
+	size     int64
+	inflight map[digest.Digest]*call
+}
+
+type entry struct {
+	size     int64
+	lastUsed time.Time
+}
+
+type call struct {
+	done chan struct{}
+	path string
+	err  error
+	// cancelled is set if the fill failed because the context of its caller was done
+	cancelled bool
+}
+
+// Open returns a cache storing up to maxSize bytes in dir, and indexes the files already in it
+func Open(dir string, maxSize int64) (*Cache, error) {
+	c := &Cache{
+		dir:      dir,
+		maxSize:  maxSize,
+		now:      time.Now,
+		entries:  map[digest.Digest]*entry{},
+		inflight: map[digest.Digest]*call{},
+	}
+	if err := os.MkdirAll(c.tmpDir(), 0o755); err != nil {
+		return nil, err
+	}
+	// leftovers of interrupted fills
+	tmpEntries, err := os.ReadDir(c.tmpDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range tmpEntries {
+		_ = os.Remove(filepath.Join(c.tmpDir(), e.Name()))
+	}
+	algs, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, alg := range algs {
+		if !alg.IsDir() || alg.Name() == "tmp" {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(dir, alg.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			d, err := digest.Parse(alg.Name() + ":" + f.Name())
+			if err != nil {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil {
+				return nil, err
+			}
+			c.entries[d] = &entry{size: info.Size(), lastUsed: info.ModTime()}
+			c.size += info.Size()
+		}
+	}
+	// the limit may have been lowered since the content was added
+	c.evict(digest.Digest{})
+	return c, nil
+}
+
+// Size returns the total size of the cached content
+func (c *Cache) Size() int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.size
+}
+
+// Get returns the path of the cached content, or ErrNotFound. Content which fails verification is
+// removed and reported as not found.
+func (c *Cache) Get(ctx context.Context, d digest.Digest) (string, error) {
+	c.lock.Lock()
+	_, ok := c.entries[d]
+	c.lock.Unlock()
+	if !ok {
+		return "", ErrNotFound
+	}
+	path := c.path(d)
+	if err := d.VerifyFile(path); err != nil {
+		logging.FromContext(ctx).WithField("digest", d.String()).Warnf("removing invalid cache entry: %v", err)
+		c.Remove(d)
+		return "", ErrNotFound
+	}
+	c.touch(d, path)
+	return path, nil
+}
+
+// GetOrFill returns the path of the cached content, calling fill to add it if it is not cached.
+// Concurrent calls for the same digest share a single fill. If the caller running the fill is
+// cancelled, the callers waiting for it retry the fill with their own context. The filled content
+// must match the digest.
+func (c *Cache) GetOrFill(ctx context.Context, d digest.Digest, fill FillFunc) (string, error) {
+	for {
+		if path, err := c.Get(ctx, d); err == nil {
+			return path, nil
+		}
+		c.lock.Lock()
+		if cl, ok := c.inflight[d]; ok {
+			c.lock.Unlock()
+			select {
+			case <-cl.done:
+				if cl.cancelled && ctx.Err() == nil {
+					continue
+				}
+				return cl.path, cl.err
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		cl := &call{done: make(chan struct{})}
+		c.inflight[d] = cl
+		c.lock.Unlock()
+
+		cl.path, cl.err = c.fill(ctx, d, fill)
+		cl.cancelled = cl.err != nil && ctx.Err() != nil
+		c.lock.Lock()
+		delete(c.inflight, d)
+		c.lock.Unlock()
+		close(cl.done)
+		return cl.path, cl.err
+	}
+}
+
+// Put adds the content read from r, which must match the digest
+func (c *Cache) Put(ctx context.Context, d digest.Digest, r io.Reader) (string, error) {
+	return c.GetOrFill(ctx, d, func(_ context.Context, w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// Remove deletes the content from the cache
+func (c *Cache) Remove(d digest.Digest) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.remove(d)
+}
+
+func (c *Cache) fill(ctx context.Context, d digest.Digest, fill FillFunc) (string, error) {
+	digester, err := digest.NewDigester(d.Algorithm)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(c.tmpDir(), "fill-")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	err = fill(ctx, io.MultiWriter(tmp, digester))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if actual := digester.Digest(); actual != d {
+		return "", fmt.Errorf("%w: expected %s, got %s", digest.ErrMismatch, d, actual)
+	}
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+	path := c.path(d)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// the file of an entry which failed verification has just been replaced
+	if old, ok := c.entries[d]; ok {
+		c.size -= old.size
+	}
+	c.entries[d] = &entry{size: info.Size(), lastUsed: c.now()}
+	c.size += info.Size()
+	c.evict(d)
+	return path, nil
+}
+
+// evict removes the least recently used entries other than keep until the cache fits its limit
+func (c *Cache) evict(keep digest.Digest) {
+	if c.size <= c.maxSize {
+		return
+	}
+	digests := make([]digest.Digest, 0, len(c.entries))
+	for d := range c.entries {
+		if d != keep {
+			digests = append(digests, d)
+		}
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		return c.entries[digests[i]].lastUsed.Before(c.entries[digests[j]].lastUsed)
+	})
+	for _, d := range digests {
+		if c.size <= c.maxSize {
+			return
+		}
+		c.remove(d)
+	}
+}
+
+func (c *Cache) remove(d digest.Digest) {
+	e, ok := c.entries[d]
+	if !ok {
+		return
+	}
+	delete(c.entries, d)
+	c.size -= e.size
+	_ = os.Remove(c.path(d))
+}
+
+// touch marks the entry as used. The modification time of the file is updated too, so that the
+// order of use survives a restart.
+func (c *Cache) touch(d digest.Digest, path string) {
+	now := c.now()
+	c.lock.Lock()
+	if e, ok := c.entries[d]; ok {
+		e.lastUsed = now
+	}
+	c.lock.Unlock()
+	_ = os.Chtimes(path, now, now)
+}
+
+func (c *Cache) path(d digest.Digest) string {
+	return filepath.Join(c.dir, string(d.Algorithm), d.Hex)
+}
+
+func (c *Cache) tmpDir() string {
+	return filepath.Join(c.dir, "tmp")
+}
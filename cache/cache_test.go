@@ -0,0 +1,157 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/argoproj/pkg/v2/digest"
+)
+
+func digestOf(t *testing.T, s string) digest.Digest {
+	d, err := digest.FromBytes(digest.SHA256, []byte(s))
+	require.NoError(t, err)
+	return d
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c, err := Open(dir, 10)
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	a, b, cc := digestOf(t, "aaaa"), digestOf(t, "bbbb"), digestOf(t, "cccc")
+	_, err = c.Get(ctx, a)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	path, err := c.Put(ctx, a, strings.NewReader("aaaa"))
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaa", string(data))
+	_, err = c.Put(ctx, b, strings.NewReader("bbbb"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), c.Size())
+
+	// a is used more recently than b, so b is evicted to make room for c
+	_, err = c.Get(ctx, a)
+	require.NoError(t, err)
+	_, err = c.Put(ctx, cc, strings.NewReader("cccc"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), c.Size())
+	_, err = c.Get(ctx, b)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = c.Get(ctx, a)
+	require.NoError(t, err)
+
+	// content must match its digest
+	_, err = c.Put(ctx, b, strings.NewReader("not b"))
+	require.ErrorIs(t, err, digest.ErrMismatch)
+
+	// corrupted files are dropped
+	require.NoError(t, os.WriteFile(path, []byte("corrupt"), 0o644))
+	_, err = c.Get(ctx, a)
+	require.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int64(4), c.Size())
+
+	// the index is rebuilt when the cache is reopened
+	reopened, err := Open(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), reopened.Size())
+	_, err = reopened.Get(ctx, cc)
+	require.NoError(t, err)
+
+	// content over a lowered limit is evicted when the cache is opened
+	shrunk, err := Open(dir, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), shrunk.Size())
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGetOrFillConcurrent(t *testing.T) {
+	ctx := context.Background()
+	c, err := Open(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	d := digestOf(t, "content")
+	var fills atomic.Int32
+	release := make(chan struct{})
+	fill := func(_ context.Context, w io.Writer) error {
+		fills.Add(1)
+		<-release
+		_, err := io.WriteString(w, "content")
+		return err
+	}
+	var wg sync.WaitGroup
+	paths := make([]string, 10)
+	for i := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			path, err := c.GetOrFill(ctx, d, fill)
+			assert.NoError(t, err)
+			paths[i] = path
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), fills.Load())
+	for _, p := range paths {
+		assert.Equal(t, paths[0], p)
+	}
+
+	_, err = c.GetOrFill(ctx, digestOf(t, "other"), func(context.Context, io.Writer) error {
+		return errors.New("download failed")
+	})
+	require.EqualError(t, err, "download failed")
+	files, err := os.ReadDir(c.tmpDir())
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestGetOrFillCancelled(t *testing.T) {
+	c, err := Open(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	d := digestOf(t, "content")
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrFill(ctx, d, func(ctx context.Context, _ io.Writer) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		errs <- err
+	}()
+	<-started
+
+	// a waiter whose context is still valid fills the content itself
+	paths := make(chan string, 1)
+	go func() {
+		path, err := c.GetOrFill(context.Background(), d, func(_ context.Context, w io.Writer) error {
+			_, err := io.WriteString(w, "content")
+			return err
+		})
+		assert.NoError(t, err)
+		paths <- path
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-errs, context.Canceled)
+	data, err := os.ReadFile(<-paths)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	sigsjson "sigs.k8s.io/json"
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/pkg/v2/logging"
+	argotime "github.com/argoproj/pkg/v2/time"
+	"github.com/argoproj/pkg/v2/warnings"
+)
+
+// Validator is implemented by configuration types with validation beyond required fields. Validate
+// is called after defaults have been applied and the configuration has been parsed.
+type Validator interface {
+	Validate() error
+}
+
+// Duration is a time.Duration which is encoded as a string such as "1m30s". The day and week units
+// of ParseHumanDuration, e.g. "7d" or "1d12h", are accepted as well.
+type Duration time.Duration
+
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\", got %s", data)
+	}
+	if parsed, err := time.ParseDuration(s); err == nil {
+		*d = Duration(parsed)
+		return nil
+	}
+	parsed, err := argotime.ParseHumanDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration '%s'", s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Parse decodes YAML or JSON into a new T. Fields are set from their `default` tag first, e.g.
+// `default:"30s"`, and fields tagged `required:"true"` must be non-zero afterwards. Unknown fields
+// are reported as warnings through the collector of ctx rather than failing, so that older
+// binaries accept newer configuration files. Durations should use the Duration type, and
+// quantities resource.Quantity.
+func Parse[T any](ctx context.Context, data []byte) (*T, error) {
+	cfg := new(T)
+	if err := applyDefaults(reflect.ValueOf(cfg).Elem(), ""); err != nil {
+		return nil, err
+	}
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		strictErrs, err := sigsjson.UnmarshalStrict(data, cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range strictErrs {
+			warnings.Addf(ctx, "config: %v", e)
+		}
+	}
+	var missing []string
+	checkRequired(reflect.ValueOf(cfg).Elem(), "", &missing)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+	if v, ok := any(cfg).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// Load reads and parses the file at path
+func Load[T any](ctx context.Context, path string) (*T, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse[T](ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Watch loads the file and returns the configuration, then checks the file for changes
+// periodically until the context is cancelled. onChange is called with the new configuration when
+// the contents of the file changed and the new contents are valid. Invalid contents are logged and
+// the previous configuration stays in effect. The period must be positive.
+func Watch[T any](ctx context.Context, path string, period time.Duration, onChange func(*T)) (*T, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("invalid watch period %v, must be positive", period)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Parse[T](ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				latest, err := os.ReadFile(path)
+				if err != nil {
+					logging.FromContext(ctx).Warnf("could not read config %s: %v", path, err)
+					continue
+				}
+				if bytes.Equal(latest, data) {
+					continue
+				}
+				data = latest
+				cfg, err := Parse[T](ctx, data)
+				if err != nil {
+					logging.FromContext(ctx).Warnf("could not reload config %s: %v", path, err)
+					continue
+				}
+				logging.FromContext(ctx).WithField("path", path).Infof("Reloaded config")
+				onChange(cfg)
+			}
+		}
+	}()
+	return cfg, nil
+}
+
+// applyDefaults sets the zero fields of the struct v from their default tags. A default is decoded
+// as JSON if possible, and as a JSON string otherwise, so that both `default:"3"` and
+// `default:"30s"` work.
+func applyDefaults(v reflect.Value, path string) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := fieldPath(path, field)
+		if def, ok := field.Tag.Lookup("default"); ok && fv.IsZero() {
+			ptr := fv.Addr().Interface()
+			if err := json.Unmarshal([]byte(def), ptr); err != nil {
+				quoted, _ := json.Marshal(def)
+				if err := json.Unmarshal(quoted, ptr); err != nil {
+					return fmt.Errorf("invalid default for %s: %w", name, err)
+				}
+			}
+		}
+		if fv.Kind() == reflect.Struct {
+			if err := applyDefaults(fv, prefix(name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkRequired(v reflect.Value, path string, missing *[]string) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		name := fieldPath(path, field)
+		if field.Tag.Get("required") == "true" && fv.IsZero() {
+			*missing = append(*missing, name)
+		}
+		if fv.Kind() == reflect.Struct {
+			checkRequired(fv, prefix(name), missing)
+		}
+	}
+}
+
+// fieldPath returns the dotted JSON path of the field for error messages. The fields of embedded
+// structs without a JSON name are promoted to the parent, as in encoding/json.
+func fieldPath(path string, field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" && field.Anonymous {
+		return strings.TrimSuffix(path, ".")
+	}
+	if name == "" || name == "-" {
+		name = field.Name
+	}
+	return path + name
+}
+
+func prefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + "."
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/argoproj/pkg/v2/warnings"
+)
+
+type Common struct {
+	LogLevel string `json:"logLevel" default:"info"`
+}
+
+type testConfig struct {
+	Common
+	Server struct {
+		Addr    string   `json:"addr" required:"true"`
+		Timeout Duration `json:"timeout" default:"30s"`
+	} `json:"server"`
+	Workers     int               `json:"workers" default:"4"`
+	Verbose     bool              `json:"verbose" default:"true"`
+	Retention   Duration          `json:"retention" default:"7d"`
+	Memory      resource.Quantity `json:"memory" default:"1Gi"`
+	Tags        []string          `json:"tags" default:"[\"a\",\"b\"]"`
+	Unset       string            `json:"unset"`
+	Invalidated bool              `json:"invalidated"`
+}
+
+func (c *testConfig) Validate() error {
+	if c.Invalidated {
+		return errors.New("invalidated")
+	}
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	collector := warnings.NewCollector()
+	ctx := warnings.NewContext(context.Background(), collector)
+	cfg, err := Parse[testConfig](ctx, []byte(`
+server:
+  addr: ":8080"
+  tiemout: 1m
+workers: 8
+verbose: false
+memory: 512Mi
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, 30*time.Second, cfg.Server.Timeout.Duration())
+	assert.Equal(t, 8, cfg.Workers)
+	// an explicit false overrides the default
+	assert.False(t, cfg.Verbose)
+	assert.Equal(t, 7*24*time.Hour, cfg.Retention.Duration())
+	assert.Equal(t, "512Mi", cfg.Memory.String())
+	assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+	assert.Empty(t, cfg.Unset)
+	require.Len(t, collector.Warnings(), 1)
+	assert.Contains(t, collector.Warnings()[0].Message, `unknown field "server.tiemout"`)
+
+	_, err = Parse[testConfig](ctx, []byte(`{"workers": 1}`))
+	require.EqualError(t, err, "missing required fields: server.addr")
+	_, err = Parse[testConfig](ctx, []byte(`{"server": {"addr": ":80"}, "invalidated": true}`))
+	require.EqualError(t, err, "invalidated")
+	_, err = Parse[testConfig](ctx, []byte(`{"server": {"addr": ":80", "timeout": 30}}`))
+	require.Error(t, err)
+	_, err = Parse[testConfig](ctx, []byte(`{"server": {"addr": ":80", "timeout": "soon"}}`))
+	require.ErrorContains(t, err, "invalid duration 'soon'")
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server: {addr: ':80'}"), 0o644))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan *testConfig, 10)
+	cfg, err := Watch[testConfig](ctx, path, 10*time.Millisecond, func(c *testConfig) { changes <- c })
+	require.NoError(t, err)
+	assert.Equal(t, ":80", cfg.Server.Addr)
+
+	// invalid contents are ignored
+	require.NoError(t, os.WriteFile(path, []byte("server: {}"), 0o644))
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, os.WriteFile(path, []byte("server: {addr: ':81'}"), 0o644))
+	select {
+	case c := <-changes:
+		assert.Equal(t, ":81", c.Server.Addr)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+	assert.Empty(t, changes)
+
+	_, err = Watch[testConfig](ctx, path, 0, func(c *testConfig) {})
+	require.Error(t, err)
+
+	_, err = Load[testConfig](context.Background(), filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
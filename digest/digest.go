@@ -0,0 +1,226 @@
+package digest
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"lukechampine.com/blake3"
+)
+
+// Algorithm names a hash algorithm
+type Algorithm string
+
+const (
+	SHA256 Algorithm = "sha256"
+	SHA512 Algorithm = "sha512"
+	BLAKE3 Algorithm = "blake3"
+	// CRC32C is the Castagnoli CRC used by S3 and GCS for object checksums. It detects corruption
+	// but is not collision resistant.
+	CRC32C Algorithm = "crc32c"
+
+	// DefaultAlgorithm is used where no algorithm is configured
+	DefaultAlgorithm = SHA256
+)
+
+// ErrMismatch is returned when content does not match the expected digest
+var ErrMismatch = errors.New("digest mismatch")
+
+var (
+	lock       sync.RWMutex
+	algorithms = map[Algorithm]func() hash.Hash{
+		SHA256: sha256.New,
+		SHA512: sha512.New,
+		BLAKE3: func() hash.Hash { return blake3.New(32, nil) },
+		CRC32C: func() hash.Hash { return crc32.New(crc32.MakeTable(crc32.Castagnoli)) },
+	}
+)
+
+// Register makes an additional algorithm available, or replaces the implementation of an
+// existing one
+func Register(alg Algorithm, newHash func() hash.Hash) {
+	lock.Lock()
+	defer lock.Unlock()
+	algorithms[alg] = newHash
+}
+
+// Algorithms returns the names of the available algorithms in sorted order
+func Algorithms() []Algorithm {
+	lock.RLock()
+	defer lock.RUnlock()
+	algs := make([]Algorithm, 0, len(algorithms))
+	for alg := range algorithms {
+		algs = append(algs, alg)
+	}
+	sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+	return algs
+}
+
+// ParseAlgorithm returns the algorithm with the given name, ignoring case
+func ParseAlgorithm(s string) (Algorithm, error) {
+	alg := Algorithm(strings.ToLower(s))
+	if !alg.Available() {
+		return "", fmt.Errorf("unsupported digest algorithm '%s'", s)
+	}
+	return alg, nil
+}
+
+// Available returns true if the algorithm is registered
+func (a Algorithm) Available() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	_, ok := algorithms[a]
+	return ok
+}
+
+// New returns a new hash for the algorithm
+func (a Algorithm) New() (hash.Hash, error) {
+	lock.RLock()
+	newHash, ok := algorithms[a]
+	lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest algorithm '%s'", a)
+	}
+	return newHash(), nil
+}
+
+// Digest is the hash of some content, encoded as "<algorithm>:<hex>" in text form
+type Digest struct {
+	Algorithm Algorithm
+	Hex       string
+}
+
+// Parse parses a digest in "<algorithm>:<hex>" form
+func Parse(s string) (Digest, error) {
+	alg, value, ok := strings.Cut(s, ":")
+	if !ok {
+		return Digest{}, fmt.Errorf("invalid digest '%s': missing algorithm", s)
+	}
+	algorithm, err := ParseAlgorithm(alg)
+	if err != nil {
+		return Digest{}, err
+	}
+	if _, err := hex.DecodeString(value); err != nil || value == "" {
+		return Digest{}, fmt.Errorf("invalid digest '%s': malformed hex value", s)
+	}
+	return Digest{Algorithm: algorithm, Hex: strings.ToLower(value)}, nil
+}
+
+func (d Digest) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return string(d.Algorithm) + ":" + d.Hex
+}
+
+// IsZero returns true for the zero Digest
+func (d Digest) IsZero() bool {
+	return d == Digest{}
+}
+
+func (d Digest) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Digest) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = Digest{}
+		return nil
+	}
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// FromBytes returns the digest of b
+func FromBytes(alg Algorithm, b []byte) (Digest, error) {
+	h, err := alg.New()
+	if err != nil {
+		return Digest{}, err
+	}
+	_, _ = h.Write(b)
+	return Digest{Algorithm: alg, Hex: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// FromReader returns the digest of everything read from r
+func FromReader(alg Algorithm, r io.Reader) (Digest, error) {
+	h, err := alg.New()
+	if err != nil {
+		return Digest{}, err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return Digest{}, err
+	}
+	return Digest{Algorithm: alg, Hex: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// FromFile returns the digest of the contents of the file
+func FromFile(alg Algorithm, path string) (Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Digest{}, err
+	}
+	defer f.Close()
+	return FromReader(alg, f)
+}
+
+// Digester computes a digest of the bytes written to it
+type Digester struct {
+	alg Algorithm
+	h   hash.Hash
+}
+
+// NewDigester returns a Digester for the algorithm
+func NewDigester(alg Algorithm) (*Digester, error) {
+	h, err := alg.New()
+	if err != nil {
+		return nil, err
+	}
+	return &Digester{alg: alg, h: h}, nil
+}
+
+func (d *Digester) Write(p []byte) (int, error) {
+	return d.h.Write(p)
+}
+
+// Digest returns the digest of the bytes written so far
+func (d *Digester) Digest() Digest {
+	return Digest{Algorithm: d.alg, Hex: hex.EncodeToString(d.h.Sum(nil))}
+}
+
+// Verify reads r to the end and returns an error wrapping ErrMismatch if its digest differs
+func (d Digest) Verify(r io.Reader) error {
+	actual, err := FromReader(d.Algorithm, r)
+	if err != nil {
+		return err
+	}
+	if actual != d {
+		return fmt.Errorf("%w: expected %s, got %s", ErrMismatch, d, actual)
+	}
+	return nil
+}
+
+// VerifyFile returns an error wrapping ErrMismatch if the digest of the file differs
+func (d Digest) VerifyFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := d.Verify(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
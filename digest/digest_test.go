@@ -0,0 +1,87 @@
+package digest
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlgorithms(t *testing.T) {
+	testData := map[Algorithm]string{
+		SHA256: "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+		SHA512: "309ecc489c12d6eb4cc40f50c902f2b4d0ed77ee511a7c7a9bcd3ca86d4cd86f989dd35bc5ff499670da34255b45b0cfd830e81f605dcf7dc5542e93ae9cd76f",
+		BLAKE3: "d74981efa70a0c880b8d8c1985d075dbcbf679b99a5f9914e5aaf96b831a9e24",
+		CRC32C: "c99465aa",
+	}
+	for alg, expected := range testData {
+		t.Run(string(alg), func(t *testing.T) {
+			d, err := FromBytes(alg, []byte("hello world"))
+			require.NoError(t, err)
+			assert.Equal(t, Digest{Algorithm: alg, Hex: expected}, d)
+			d, err = FromReader(alg, strings.NewReader("hello world"))
+			require.NoError(t, err)
+			assert.Equal(t, expected, d.Hex)
+		})
+	}
+	_, err := FromBytes("md5", nil)
+	require.EqualError(t, err, "unsupported digest algorithm 'md5'")
+
+	Register("md5", md5.New)
+	defer func() {
+		lock.Lock()
+		delete(algorithms, "md5")
+		lock.Unlock()
+	}()
+	assert.Equal(t, []Algorithm{BLAKE3, CRC32C, "md5", SHA256, SHA512}, Algorithms())
+	alg, err := ParseAlgorithm("MD5")
+	require.NoError(t, err)
+	d, err := FromBytes(alg, []byte("hello world"))
+	require.NoError(t, err)
+	assert.Equal(t, "md5:5eb63bbbe01eeed093cb22bb8f5acdc3", d.String())
+}
+
+func TestParse(t *testing.T) {
+	d, err := Parse("SHA256:ABCD")
+	require.NoError(t, err)
+	assert.Equal(t, Digest{Algorithm: SHA256, Hex: "abcd"}, d)
+	for _, s := range []string{"abcd", "sha256:xyz", "sha256:", "sha1:abcd"} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+
+	var v struct {
+		Digest Digest `json:"digest"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"digest":"crc32c:c99465aa"}`), &v))
+	assert.Equal(t, Digest{Algorithm: CRC32C, Hex: "c99465aa"}, v.Digest)
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"digest":"crc32c:c99465aa"}`, string(data))
+	require.Error(t, json.Unmarshal([]byte(`{"digest":"bogus"}`), &v))
+}
+
+func TestVerify(t *testing.T) {
+	digester, err := NewDigester(SHA256)
+	require.NoError(t, err)
+	_, _ = digester.Write([]byte("hello "))
+	_, _ = digester.Write([]byte("world"))
+	d := digester.Digest()
+	assert.Equal(t, "sha256:b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", d.String())
+
+	path := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(path, []byte("hello world"), 0o644))
+	require.NoError(t, d.VerifyFile(path))
+	fromFile, err := FromFile(SHA256, path)
+	require.NoError(t, err)
+	assert.Equal(t, d, fromFile)
+
+	err = d.Verify(strings.NewReader("hello"))
+	require.ErrorIs(t, err, ErrMismatch)
+	assert.Contains(t, err.Error(), "expected sha256:b94d")
+}
@@ -0,0 +1,123 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// New, Is, As, Join and Unwrap are those of the standard library, so that importing this package
+// does not require importing both
+var (
+	New    = errors.New
+	Is     = errors.Is
+	As     = errors.As
+	Join   = errors.Join
+	Unwrap = errors.Unwrap
+)
+
+type classified struct {
+	err       error
+	transient bool
+}
+
+func (c *classified) Error() string {
+	return c.err.Error()
+}
+
+func (c *classified) Unwrap() error {
+	return c.err
+}
+
+// Transient marks err as transient, i.e. the operation may succeed if it is retried. It returns
+// nil if err is nil.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classified{err: err, transient: true}
+}
+
+// Permanent marks err as permanent, i.e. retrying the operation is pointless. It returns nil if
+// err is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classified{err: err}
+}
+
+// IsTransient returns true if err was marked with Transient, or is a timeout such as a net.Error
+// whose Timeout method returns true. The outermost mark wins, so a transient error can be wrapped
+// as permanent and vice versa.
+func IsTransient(err error) bool {
+	var c *classified
+	if errors.As(err, &c) {
+		return c.transient
+	}
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
+
+// IsPermanent returns true if err was marked with Permanent
+func IsPermanent(err error) bool {
+	var c *classified
+	return errors.As(err, &c) && !c.transient
+}
+
+// Backoff describes the delays between the attempts of Retry
+type Backoff struct {
+	// Duration is the delay before the first retry
+	Duration time.Duration
+	// Factor multiplies the delay after every retry. Values below 1 keep the delay constant.
+	Factor float64
+	// Jitter adds a random delay of up to Jitter times the delay, so that clients which failed at
+	// the same time do not retry at the same time
+	Jitter float64
+	// Steps is the maximum number of attempts, or zero to retry until the context is done
+	Steps int
+	// Cap is the maximum delay, or zero for no maximum
+	Cap time.Duration
+}
+
+// DefaultBackoff makes 5 attempts within about 1.5 seconds
+var DefaultBackoff = Backoff{Duration: 100 * time.Millisecond, Factor: 2, Jitter: 0.1, Steps: 5, Cap: 10 * time.Second}
+
+// delay returns the delay before the given retry, starting at 0
+func (b Backoff) delay(retry int) time.Duration {
+	d := float64(b.Duration)
+	for i := 0; i < retry && b.Factor > 1; i++ {
+		d *= b.Factor
+		if b.Cap > 0 && d > float64(b.Cap) {
+			break
+		}
+	}
+	if b.Jitter > 0 {
+		d += d * b.Jitter * rand.Float64()
+	}
+	if b.Cap > 0 && d > float64(b.Cap) {
+		d = float64(b.Cap)
+	}
+	return time.Duration(d)
+}
+
+// Retry calls fn until it succeeds, returns a permanent error, the attempts of the backoff are
+// exhausted or the context is done. It returns the last error of fn, wrapped with the context
+// error if the context was done while waiting.
+func Retry(ctx context.Context, backoff Backoff, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || IsPermanent(err) || (backoff.Steps > 0 && attempt >= backoff.Steps) {
+			return err
+		}
+		timer := time.NewTimer(backoff.delay(attempt - 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassification(t *testing.T) {
+	base := New("connection refused")
+	assert.False(t, IsTransient(base))
+	assert.False(t, IsPermanent(base))
+	assert.Nil(t, Transient(nil))
+	assert.Nil(t, Permanent(nil))
+
+	transient := fmt.Errorf("failed to get pod: %w", Transient(base))
+	assert.True(t, IsTransient(transient))
+	assert.False(t, IsPermanent(transient))
+	assert.True(t, Is(transient, base))
+	assert.Equal(t, "failed to get pod: connection refused", transient.Error())
+
+	// the outermost classification wins
+	permanent := Permanent(transient)
+	assert.False(t, IsTransient(permanent))
+	assert.True(t, IsPermanent(permanent))
+
+	var err error = &net.OpError{Op: "dial", Err: timeoutError{}}
+	assert.True(t, IsTransient(err))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string { return "i/o timeout" }
+func (timeoutError) Timeout() bool { return true }
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Duration: time.Second, Factor: 2, Cap: 5 * time.Second}
+	assert.Equal(t, time.Second, b.delay(0))
+	assert.Equal(t, 4*time.Second, b.delay(2))
+	assert.Equal(t, 5*time.Second, b.delay(3))
+	assert.Equal(t, 5*time.Second, b.delay(100))
+
+	b = Backoff{Duration: time.Second, Jitter: 0.5}
+	for i := 0; i < 10; i++ {
+		d := b.delay(i)
+		assert.GreaterOrEqual(t, d, time.Second)
+		assert.LessOrEqual(t, d, 1500*time.Millisecond)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	backoff := Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3}
+	errFailed := New("failed")
+
+	attempts := 0
+	err := Retry(ctx, backoff, func() error {
+		attempts++
+		if attempts < 3 {
+			return Transient(errFailed)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = Retry(ctx, backoff, func() error {
+		attempts++
+		return errFailed
+	})
+	require.ErrorIs(t, err, errFailed)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = Retry(ctx, backoff, func() error {
+		attempts++
+		return Permanent(errFailed)
+	})
+	require.ErrorIs(t, err, errFailed)
+	assert.Equal(t, 1, attempts)
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err = Retry(ctx, Backoff{Duration: time.Hour}, func() error { return errFailed })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, err, errFailed)
+}
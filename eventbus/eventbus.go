@@ -0,0 +1,337 @@
+package eventbus
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned when publishing to a closed topic
+var ErrClosed = errors.New("topic is closed")
+
+// Policy determines what happens when an event is published to a subscriber whose buffer is full
+type Policy int
+
+const (
+	// Block makes the publisher wait until the subscriber has room in its buffer
+	Block Policy = iota
+	// DropOldest discards the oldest buffered event to make room for the new one
+	DropOldest
+	// Coalesce replaces a buffered event which has the same key as the new one. If no such event is
+	// buffered and the buffer is full, the oldest event is dropped.
+	Coalesce
+)
+
+// DefaultBufferSize is the buffer size of a subscription when none is specified
+const DefaultBufferSize = 64
+
+// SubscribeOptions configures a subscription
+type SubscribeOptions[T any] struct {
+	// BufferSize is the maximum number of undelivered events held for the subscriber
+	BufferSize int
+	// Policy is applied when the buffer is full
+	Policy Policy
+	// Key returns the coalescing key of an event. Required for the Coalesce policy.
+	Key func(T) string
+}
+
+// Metrics are counters describing the events handled by a topic or subscription
+type Metrics struct {
+	Published uint64
+	Delivered uint64
+	Dropped   uint64
+	Coalesced uint64
+}
+
+// Topic is a typed publish/subscribe channel. Events are delivered to every subscriber in the order
+// they were published.
+type Topic[T any] struct {
+	name string
+
+	lock      sync.RWMutex
+	subs      map[*Subscription[T]]struct{}
+	closed    bool
+	done      chan struct{}
+	published atomic.Uint64
+	// metrics of subscriptions which have been closed
+	retired Metrics
+}
+
+// NewTopic returns a new topic with the given name
+func NewTopic[T any](name string) *Topic[T] {
+	return &Topic[T]{
+		name: name,
+		subs: map[*Subscription[T]]struct{}{},
+		done: make(chan struct{}),
+	}
+}
+
+// Name returns the name of the topic
+func (t *Topic[T]) Name() string {
+	return t.name
+}
+
+// Subscribe registers a new subscriber. Events published after Subscribe returns are delivered on
+// the subscription's channel.
+func (t *Topic[T]) Subscribe(opts SubscribeOptions[T]) *Subscription[T] {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.Policy == Coalesce && opts.Key == nil {
+		panic("eventbus: Coalesce policy requires a Key function")
+	}
+	s := &Subscription[T]{
+		topic:    t,
+		opts:     opts,
+		queue:    list.New(),
+		keys:     map[string]*list.Element{},
+		out:      make(chan T),
+		notEmpty: make(chan struct{}, 1),
+		notFull:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		close(s.out)
+		return s
+	}
+	t.subs[s] = struct{}{}
+	go s.run()
+	return s
+}
+
+// Publish delivers the event to all subscribers. It only blocks if a subscriber uses the Block
+// policy and has a full buffer, in which case that subscriber misses the event once the context is
+// done or the topic is closed meanwhile. The event is still delivered to the other subscribers, and
+// the errors of those which missed it are joined.
+func (t *Topic[T]) Publish(ctx context.Context, event T) error {
+	t.lock.RLock()
+	if t.closed {
+		t.lock.RUnlock()
+		return ErrClosed
+	}
+	t.published.Add(1)
+	subs := make([]*Subscription[T], 0, len(t.subs))
+	for s := range t.subs {
+		subs = append(subs, s)
+	}
+	// the lock is not held while pushing, so that a blocked publisher does not block the topic
+	t.lock.RUnlock()
+	var errs []error
+	for _, s := range subs {
+		if err := s.push(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops accepting new events. Subscribers receive the events which are already buffered
+// before their channels are closed.
+func (t *Topic[T]) Close() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
+	close(t.done)
+	for s := range t.subs {
+		s.finish()
+	}
+}
+
+// Metrics returns the accumulated counters of the topic and all its subscriptions
+func (t *Topic[T]) Metrics() Metrics {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	m := t.retired
+	m.Published = t.published.Load()
+	for s := range t.subs {
+		sm := s.Metrics()
+		m.Delivered += sm.Delivered
+		m.Dropped += sm.Dropped
+		m.Coalesced += sm.Coalesced
+	}
+	return m
+}
+
+func (t *Topic[T]) unsubscribe(s *Subscription[T]) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, ok := t.subs[s]; !ok {
+		return
+	}
+	delete(t.subs, s)
+	sm := s.Metrics()
+	t.retired.Delivered += sm.Delivered
+	t.retired.Dropped += sm.Dropped
+	t.retired.Coalesced += sm.Coalesced
+}
+
+type entry[T any] struct {
+	key   string
+	value T
+}
+
+// Subscription receives the events published to a topic
+type Subscription[T any] struct {
+	topic *Topic[T]
+	opts  SubscribeOptions[T]
+
+	lock     sync.Mutex
+	queue    *list.List
+	keys     map[string]*list.Element
+	finished bool
+
+	out       chan T
+	notEmpty  chan struct{}
+	notFull   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	published atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	coalesced atomic.Uint64
+}
+
+// C returns the channel on which events are delivered. It is closed when the subscription or the
+// topic is closed.
+func (s *Subscription[T]) C() <-chan T {
+	return s.out
+}
+
+// Close unsubscribes from the topic and discards any buffered events
+func (s *Subscription[T]) Close() {
+	// signal done first to release any publisher blocked on this subscription
+	s.closeOnce.Do(func() { close(s.done) })
+	s.topic.unsubscribe(s)
+}
+
+// Metrics returns the counters of this subscription
+func (s *Subscription[T]) Metrics() Metrics {
+	return Metrics{
+		Published: s.published.Load(),
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+		Coalesced: s.coalesced.Load(),
+	}
+}
+
+func (s *Subscription[T]) push(ctx context.Context, event T) error {
+	var key string
+	if s.opts.Policy == Coalesce {
+		key = s.opts.Key(event)
+	}
+	for {
+		s.lock.Lock()
+		if s.finished {
+			s.lock.Unlock()
+			return ErrClosed
+		}
+		if s.opts.Policy == Coalesce {
+			if e, ok := s.keys[key]; ok {
+				e.Value.(*entry[T]).value = event
+				s.lock.Unlock()
+				s.published.Add(1)
+				s.coalesced.Add(1)
+				return nil
+			}
+		}
+		if s.queue.Len() >= s.opts.BufferSize {
+			if s.opts.Policy == Block {
+				s.lock.Unlock()
+				select {
+				case <-s.notFull:
+					continue
+				case <-s.done:
+					return nil
+				case <-s.topic.done:
+					return ErrClosed
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			s.removeFront()
+			s.dropped.Add(1)
+		}
+		e := s.queue.PushBack(&entry[T]{key: key, value: event})
+		if s.opts.Policy == Coalesce {
+			s.keys[key] = e
+		}
+		s.lock.Unlock()
+		s.published.Add(1)
+		signal(s.notEmpty)
+		return nil
+	}
+}
+
+// pop blocks until an event is available. It returns false once the subscription is finished and
+// the buffer is empty, or the subscription is closed.
+func (s *Subscription[T]) pop() (T, bool) {
+	for {
+		s.lock.Lock()
+		if s.queue.Len() > 0 {
+			value := s.removeFront()
+			s.lock.Unlock()
+			signal(s.notFull)
+			return value, true
+		}
+		finished := s.finished
+		s.lock.Unlock()
+		if finished {
+			var zero T
+			return zero, false
+		}
+		select {
+		case <-s.notEmpty:
+		case <-s.done:
+			var zero T
+			return zero, false
+		}
+	}
+}
+
+// removeFront must be called with the lock held
+func (s *Subscription[T]) removeFront() T {
+	e := s.queue.Remove(s.queue.Front()).(*entry[T])
+	if s.opts.Policy == Coalesce {
+		delete(s.keys, e.key)
+	}
+	return e.value
+}
+
+// finish marks the subscription as finished, so that the channel is closed once drained
+func (s *Subscription[T]) finish() {
+	s.lock.Lock()
+	s.finished = true
+	s.lock.Unlock()
+	signal(s.notEmpty)
+}
+
+func (s *Subscription[T]) run() {
+	defer close(s.out)
+	for {
+		event, ok := s.pop()
+		if !ok {
+			return
+		}
+		select {
+		case s.out <- event:
+			s.delivered.Add(1)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
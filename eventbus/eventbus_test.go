@@ -0,0 +1,151 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	key   string
+	value int
+}
+
+func collect[T any](s *Subscription[T]) []T {
+	var events []T
+	for e := range s.C() {
+		events = append(events, e)
+	}
+	return events
+}
+
+func buffered[T any](s *Subscription[T]) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.queue.Len()
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	topic := NewTopic[int]("numbers")
+	assert.Equal(t, "numbers", topic.Name())
+	a := topic.Subscribe(SubscribeOptions[int]{})
+	b := topic.Subscribe(SubscribeOptions[int]{})
+	for i := 0; i < 5; i++ {
+		require.NoError(t, topic.Publish(context.Background(), i))
+	}
+	topic.Close()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, collect(a))
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, collect(b))
+	assert.Equal(t, Metrics{Published: 5, Delivered: 10}, topic.Metrics())
+	assert.ErrorIs(t, topic.Publish(context.Background(), 5), ErrClosed)
+}
+
+func TestDropOldest(t *testing.T) {
+	topic := NewTopic[int]("numbers")
+	s := topic.Subscribe(SubscribeOptions[int]{BufferSize: 2, Policy: DropOldest})
+	// the first event is picked up by the delivery goroutine and waits on the channel
+	require.NoError(t, topic.Publish(context.Background(), 0))
+	assert.Eventually(t, func() bool { return buffered(s) == 0 }, time.Second, time.Millisecond)
+	for i := 1; i < 5; i++ {
+		require.NoError(t, topic.Publish(context.Background(), i))
+	}
+	topic.Close()
+	assert.Equal(t, []int{0, 3, 4}, collect(s))
+	assert.Equal(t, uint64(2), s.Metrics().Dropped)
+}
+
+func TestCoalesce(t *testing.T) {
+	topic := NewTopic[event]("events")
+	s := topic.Subscribe(SubscribeOptions[event]{Policy: Coalesce, Key: func(e event) string { return e.key }})
+	require.NoError(t, topic.Publish(context.Background(), event{"blocker", 0}))
+	assert.Eventually(t, func() bool { return buffered(s) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, topic.Publish(context.Background(), event{"a", 1}))
+	require.NoError(t, topic.Publish(context.Background(), event{"b", 1}))
+	require.NoError(t, topic.Publish(context.Background(), event{"a", 2}))
+	topic.Close()
+	assert.Equal(t, []event{{"blocker", 0}, {"a", 2}, {"b", 1}}, collect(s))
+	assert.Equal(t, uint64(1), s.Metrics().Coalesced)
+}
+
+func TestBlock(t *testing.T) {
+	topic := NewTopic[int]("numbers")
+	s := topic.Subscribe(SubscribeOptions[int]{BufferSize: 1, Policy: Block})
+	require.NoError(t, topic.Publish(context.Background(), 0))
+	assert.Eventually(t, func() bool { return buffered(s) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, topic.Publish(context.Background(), 1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, topic.Publish(ctx, 2), context.DeadlineExceeded)
+
+	published := make(chan error)
+	go func() {
+		published <- topic.Publish(context.Background(), 3)
+	}()
+	assert.Equal(t, 0, <-s.C())
+	require.NoError(t, <-published)
+	topic.Close()
+	assert.Equal(t, []int{1, 3}, collect(s))
+}
+
+func TestBlockOtherSubscribers(t *testing.T) {
+	topic := NewTopic[int]("numbers")
+	blocked := topic.Subscribe(SubscribeOptions[int]{BufferSize: 1, Policy: Block})
+	require.NoError(t, topic.Publish(context.Background(), 0))
+	assert.Eventually(t, func() bool { return buffered(blocked) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, topic.Publish(context.Background(), 1))
+
+	// a full subscriber does not keep the event from the others
+	other := topic.Subscribe(SubscribeOptions[int]{Policy: Block})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, topic.Publish(ctx, 2), context.DeadlineExceeded)
+	assert.Equal(t, 2, <-other.C())
+	assert.Equal(t, uint64(2), blocked.Metrics().Published)
+	assert.Equal(t, uint64(1), other.Metrics().Published)
+}
+
+func TestBlockedPublish(t *testing.T) {
+	topic := NewTopic[int]("numbers")
+	s := topic.Subscribe(SubscribeOptions[int]{BufferSize: 1, Policy: Block})
+	// one event is held by the delivery goroutine, one is buffered
+	require.NoError(t, topic.Publish(context.Background(), 0))
+	assert.Eventually(t, func() bool { return buffered(s) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, topic.Publish(context.Background(), 1))
+	published := make(chan error)
+	go func() {
+		published <- topic.Publish(context.Background(), 2)
+	}()
+
+	assert.Eventually(t, func() bool { return topic.Metrics().Published == 3 }, time.Second, time.Millisecond)
+
+	// the blocked publisher does not block the topic
+	other := topic.Subscribe(SubscribeOptions[int]{})
+	topic.Close()
+	require.ErrorIs(t, <-published, ErrClosed)
+	assert.Equal(t, []int{0, 1}, collect(s))
+	assert.Empty(t, collect(other))
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	topic := NewTopic[int]("numbers")
+	s := topic.Subscribe(SubscribeOptions[int]{BufferSize: 1, Policy: Block})
+	require.NoError(t, topic.Publish(context.Background(), 0))
+	require.NoError(t, topic.Publish(context.Background(), 1))
+
+	published := make(chan error)
+	go func() {
+		published <- topic.Publish(context.Background(), 2)
+	}()
+	s.Close()
+	require.NoError(t, <-published)
+	assert.Empty(t, collect(s))
+
+	require.NoError(t, topic.Publish(context.Background(), 3))
+	topic.Close()
+	assert.Empty(t, collect(topic.Subscribe(SubscribeOptions[int]{})))
+}
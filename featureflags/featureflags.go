@@ -0,0 +1,277 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// Source describes where the current value of a flag came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceEnv     Source = "env"
+	SourceFile    Source = "file"
+)
+
+// Flag is a boolean feature flag declared in code
+type Flag struct {
+	registry    *Registry
+	name        string
+	description string
+	def         bool
+	// env is the value of the environment variable when the flag was declared, if it was valid
+	env *bool
+}
+
+// Name returns the name of the flag
+func (f *Flag) Name() string {
+	return f.name
+}
+
+// Enabled returns the current value of the flag
+func (f *Flag) Enabled() bool {
+	enabled, _ := f.registry.value(f)
+	return enabled
+}
+
+// Status describes the state of a flag
+type Status struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+	Enabled     bool   `json:"enabled"`
+	Source      Source `json:"source"`
+}
+
+// ChangeFunc is called when the effective value of a flag changes
+type ChangeFunc func(name string, enabled bool)
+
+// Registry holds a set of feature flags. Values are resolved with the following precedence: the
+// file loaded by LoadFile or WatchFile, then environment variables, then the declared default.
+// Environment variables are read once when a flag is declared.
+type Registry struct {
+	envPrefix string
+	logger    logging.Logger
+
+	lock      sync.RWMutex
+	flags     map[string]*Flag
+	overrides map[string]bool
+	callbacks []ChangeFunc
+}
+
+// NewRegistry returns an empty registry. Flags can be overridden by environment variables named
+// envPrefix followed by the upper-cased flag name, with dashes and dots replaced by underscores.
+func NewRegistry(envPrefix string) *Registry {
+	return &Registry{
+		envPrefix: envPrefix,
+		logger:    logging.FromContext(context.Background()),
+		flags:     map[string]*Flag{},
+		overrides: map[string]bool{},
+	}
+}
+
+// Bool declares a new flag. It panics if a flag with the same name was already declared.
+func (r *Registry) Bool(name string, def bool, description string) *Flag {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.flags[name]; ok {
+		panic(fmt.Sprintf("feature flag %s declared twice", name))
+	}
+	f := &Flag{registry: r, name: name, description: description, def: def}
+	if v, ok := os.LookupEnv(r.EnvVar(name)); ok {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			f.env = &enabled
+		} else {
+			r.logger.Warnf("invalid value '%s' for %s, using default", v, r.EnvVar(name))
+		}
+	}
+	r.flags[name] = f
+	return f
+}
+
+// SetLogger sets the logger used for invalid values and changes of flags. It defaults to the
+// logger of the logging package.
+func (r *Registry) SetLogger(l logging.Logger) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.logger = l
+}
+
+// OnChange registers a callback which is invoked whenever reloading the file changes the effective
+// value of a flag
+func (r *Registry) OnChange(fn ChangeFunc) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.callbacks = append(r.callbacks, fn)
+}
+
+// EnvVar returns the name of the environment variable which overrides the flag
+func (r *Registry) EnvVar(name string) string {
+	return r.envPrefix + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// List returns the status of all declared flags sorted by name
+func (r *Registry) List() []Status {
+	r.lock.RLock()
+	flags := make([]*Flag, 0, len(r.flags))
+	for _, f := range r.flags {
+		flags = append(flags, f)
+	}
+	r.lock.RUnlock()
+	sort.Slice(flags, func(i, j int) bool { return flags[i].name < flags[j].name })
+
+	statuses := make([]Status, 0, len(flags))
+	for _, f := range flags {
+		enabled, source := r.value(f)
+		statuses = append(statuses, Status{
+			Name:        f.name,
+			Description: f.description,
+			Default:     f.def,
+			Enabled:     enabled,
+			Source:      source,
+		})
+	}
+	return statuses
+}
+
+// Handler returns an HTTP handler which renders the status of all flags as JSON
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(r.List()); err != nil {
+			logging.FromContext(req.Context()).Warnf("could not encode feature flags: %v", err)
+		}
+	})
+}
+
+func (r *Registry) value(f *Flag) (bool, Source) {
+	r.lock.RLock()
+	override, ok := r.overrides[f.name]
+	r.lock.RUnlock()
+	if ok {
+		return override, SourceFile
+	}
+	if f.env != nil {
+		return *f.env, SourceEnv
+	}
+	return f.def, SourceDefault
+}
+
+// LoadFile reads flag values from path and notifies callbacks of any changes. The path is either a
+// YAML or JSON file mapping flag names to booleans, or a directory with one file per flag such as
+// a mounted ConfigMap. A missing path clears all file overrides.
+func (r *Registry) LoadFile(path string) error {
+	overrides, err := readOverrides(path)
+	if err != nil {
+		return err
+	}
+
+	before := map[string]bool{}
+	for _, status := range r.List() {
+		before[status.Name] = status.Enabled
+	}
+	r.lock.Lock()
+	for name := range overrides {
+		if _, ok := r.flags[name]; !ok {
+			r.logger.Warnf("ignoring unknown feature flag %s in %s", name, path)
+			delete(overrides, name)
+		}
+	}
+	r.overrides = overrides
+	callbacks := append([]ChangeFunc{}, r.callbacks...)
+	logger := r.logger
+	r.lock.Unlock()
+
+	for _, status := range r.List() {
+		if enabled, ok := before[status.Name]; ok && enabled != status.Enabled {
+			logger.WithField("flag", status.Name).Infof("Feature flag changed to %v", status.Enabled)
+			for _, fn := range callbacks {
+				fn(status.Name, status.Enabled)
+			}
+		}
+	}
+	return nil
+}
+
+// WatchFile loads the file and then reloads it periodically until the context is cancelled.
+// Reload failures are logged and the previous values are kept. The period must be positive.
+func (r *Registry) WatchFile(ctx context.Context, path string, period time.Duration) error {
+	if period <= 0 {
+		return fmt.Errorf("invalid watch period %v, must be positive", period)
+	}
+	if err := r.LoadFile(path); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.LoadFile(path); err != nil {
+					logging.FromContext(ctx).Warnf("could not reload feature flags from %s: %v", path, err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func readOverrides(path string) (map[string]bool, error) {
+	overrides := map[string]bool{}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return overrides, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, &overrides); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		return overrides, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		// ConfigMap volumes contain hidden bookkeeping entries such as ..data
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for feature flag %s: %w", e.Name(), err)
+		}
+		overrides[e.Name()] = enabled
+	}
+	return overrides, nil
+}
@@ -0,0 +1,133 @@
+package featureflags
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+type warningCounter struct {
+	warnings int
+}
+
+func (c *warningCounter) Log(level logging.Level, _ string, _ logging.Fields) {
+	if level == logging.WarnLevel {
+		c.warnings++
+	}
+}
+
+func TestDefaultsAndEnv(t *testing.T) {
+	r := NewRegistry("ARGO_FEATURE_")
+	slow := r.Bool("slow-path", false, "")
+	assert.Equal(t, "ARGO_FEATURE_CACHE_ENABLED", r.EnvVar("cache.enabled"))
+
+	t.Setenv("ARGO_FEATURE_FAST_PATH", "true")
+	t.Setenv("ARGO_FEATURE_CACHE_ENABLED", "not-a-bool")
+	t.Setenv("ARGO_FEATURE_SLOW_PATH", "true")
+	warnings := &warningCounter{}
+	r.SetLogger(logging.New(warnings, nil))
+	fast := r.Bool("fast-path", false, "Use the fast path")
+	cache := r.Bool("cache.enabled", true, "Enable the cache")
+	assert.True(t, fast.Enabled())
+	assert.True(t, cache.Enabled())
+	assert.True(t, cache.Enabled())
+	// the environment is read when the flag is declared, and invalid values are reported once
+	assert.False(t, slow.Enabled())
+	assert.Equal(t, 1, warnings.warnings)
+
+	assert.Equal(t, []Status{
+		{Name: "cache.enabled", Description: "Enable the cache", Default: true, Enabled: true, Source: SourceDefault},
+		{Name: "fast-path", Description: "Use the fast path", Default: false, Enabled: true, Source: SourceEnv},
+		{Name: "slow-path", Default: false, Enabled: false, Source: SourceDefault},
+	}, r.List())
+
+	assert.Panics(t, func() { r.Bool("fast-path", true, "") })
+}
+
+func TestLoadFile(t *testing.T) {
+	r := NewRegistry("")
+	fast := r.Bool("fast-path", false, "")
+	slow := r.Bool("slow-path", true, "")
+	var changes []string
+	r.OnChange(func(name string, enabled bool) {
+		changes = append(changes, name)
+	})
+
+	path := filepath.Join(t.TempDir(), "flags.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("fast-path: true\nslow-path: true\nunknown: true\n"), 0o600))
+	require.NoError(t, r.LoadFile(path))
+	assert.True(t, fast.Enabled())
+	assert.True(t, slow.Enabled())
+	assert.Equal(t, []string{"fast-path"}, changes)
+
+	// removing the file reverts to the defaults
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, r.LoadFile(path))
+	assert.False(t, fast.Enabled())
+	assert.Equal(t, []string{"fast-path", "fast-path"}, changes)
+
+	require.NoError(t, os.WriteFile(path, []byte("fast-path: maybe"), 0o600))
+	require.Error(t, r.LoadFile(path))
+}
+
+func TestLoadConfigMapDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fast-path"), []byte("true\n"), 0o600))
+
+	r := NewRegistry("")
+	fast := r.Bool("fast-path", false, "")
+	require.NoError(t, r.LoadFile(dir))
+	assert.True(t, fast.Enabled())
+	assert.Equal(t, SourceFile, r.List()[0].Source)
+}
+
+func TestWatchFile(t *testing.T) {
+	r := NewRegistry("")
+	fast := r.Bool("fast-path", false, "")
+	var lock sync.Mutex
+	changed := false
+	r.OnChange(func(name string, enabled bool) {
+		lock.Lock()
+		defer lock.Unlock()
+		changed = enabled
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "flags.json")
+	require.NoError(t, r.WatchFile(ctx, path, 10*time.Millisecond))
+	assert.False(t, fast.Enabled())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"fast-path": true}`), 0o600))
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return changed
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, fast.Enabled())
+
+	require.Error(t, r.WatchFile(ctx, path, 0))
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry("")
+	r.Bool("fast-path", true, "Use the fast path")
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/featureflags", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var statuses []Status
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &statuses))
+	assert.Equal(t, []Status{{Name: "fast-path", Description: "Use the fast path", Default: true, Enabled: true, Source: SourceDefault}}, statuses)
+}
@@ -0,0 +1,65 @@
+package file
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/argoproj/pkg/v2/digest"
+)
+
+// WriteAtomic writes data to the file at path, so that readers see either the previous or the new
+// contents but never a partial write, even if the process crashes. The data is written to a
+// temporary file in the same directory, synced to disk and renamed over path.
+func WriteAtomic(path string, data []byte, perm fs.FileMode) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir persists the rename of a file in the directory. Directories cannot be synced on Windows,
+// where the rename is durable once it returns.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Checksum returns the digest of the contents of the file at path
+func Checksum(path string, alg digest.Algorithm) (digest.Digest, error) {
+	return digest.FromFile(alg, path)
+}
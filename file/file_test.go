@@ -0,0 +1,255 @@
+package file
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/argoproj/pkg/v2/digest"
+	"github.com/argoproj/pkg/v2/progress"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	for path, content := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestManifest(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a/b":     "hello",
+		"a.txt":   "world",
+		"c/d/e":   "",
+		"removed": "x",
+	})
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Symlink("a.txt", filepath.Join(root, "link")))
+	}
+
+	m, err := CreateManifest(root)
+	require.NoError(t, err)
+	var paths []string
+	for _, e := range m.Entries {
+		paths = append(paths, e.Path)
+	}
+	expected := []string{"a", "a.txt", "a/b", "c", "c/d", "c/d/e", "removed"}
+	if runtime.GOOS != "windows" {
+		expected = []string{"a", "a.txt", "a/b", "c", "c/d", "c/d/e", "link", "removed"}
+		assert.Equal(t, "a.txt", m.Entries[6].Link)
+	}
+	assert.Equal(t, expected, paths)
+	assert.Equal(t, Entry{
+		Path:   "a/b",
+		Mode:   m.Entries[2].Mode,
+		Size:   5,
+		Digest: &digest.Digest{Algorithm: digest.SHA256, Hex: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+	}, m.Entries[2])
+	assert.True(t, m.Entries[0].Mode.IsDir())
+
+	// the manifest survives a round trip through JSON, directories have no digest
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"digest":""`)
+	m = &Manifest{}
+	require.NoError(t, json.Unmarshal(data, m))
+
+	r, err := VerifyManifest(root, m)
+	require.NoError(t, err)
+	assert.True(t, r.OK())
+
+	writeTree(t, root, map[string]string{"a/b": "jello", "new": "", "c/d/f": "f"})
+	require.NoError(t, os.Remove(filepath.Join(root, "removed")))
+	r, err = VerifyManifest(root, m)
+	require.NoError(t, err)
+	assert.False(t, r.OK())
+	assert.Equal(t, &Report{
+		Added:    []string{"c/d/f", "new"},
+		Missing:  []string{"removed"},
+		Modified: []string{"a/b"},
+	}, r)
+	assert.Equal(t, "2 added, 1 missing, 1 modified", r.String())
+}
+
+func TestManifestAlgorithm(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a": "hello"})
+	m, err := CreateManifest(root, WithAlgorithm(digest.BLAKE3))
+	require.NoError(t, err)
+	assert.Equal(t, digest.BLAKE3, m.Entries[0].Digest.Algorithm)
+	r, err := VerifyManifest(root, m)
+	require.NoError(t, err)
+	assert.True(t, r.OK())
+
+	_, err = CreateManifest(root, WithAlgorithm("md4"))
+	require.Error(t, err)
+
+	m.Entries[0].Digest = &digest.Digest{Algorithm: digest.BLAKE3, Hex: "00"}
+	r, err = VerifyManifest(root, m)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, r.Modified)
+}
+
+func TestManifestExcludes(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a":             "a",
+		"b.tmp":         "b",
+		"dir/c.tmp":     "c",
+		"dir/d":         "d",
+		"cache/e":       "e",
+		"cache/f/g":     "g",
+		"nested/cache":  "h",
+		"keep/cache.go": "i",
+	})
+	m, err := CreateManifest(root, WithExcludes("*.tmp", "cache"))
+	require.NoError(t, err)
+	var paths []string
+	for _, e := range m.Entries {
+		paths = append(paths, e.Path)
+	}
+	assert.Equal(t, []string{"a", "dir", "dir/d", "keep", "keep/cache.go", "nested"}, paths)
+
+	// excluded files may change freely
+	full, err := CreateManifest(root)
+	require.NoError(t, err)
+	writeTree(t, root, map[string]string{"cache/e": "changed", "new.tmp": ""})
+	r, err := VerifyManifest(root, full, WithExcludes("*.tmp", "cache"))
+	require.NoError(t, err)
+	assert.True(t, r.OK(), r.String())
+}
+
+func TestManifestProgress(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{"a": "hello", "b/c": "world"})
+	var events []progress.Event
+	_, err := CreateManifest(root, WithProgress(progress.ReporterFunc(func(e progress.Event) { events = append(events, e) })))
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, "manifest", last.Operation)
+	assert.Equal(t, int64(10), last.BytesDone)
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, WriteAtomic(path, []byte("old"), 0o600))
+	require.NoError(t, WriteAtomic(path, []byte("new"), 0o644))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+	}
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	d, err := Checksum(path, digest.SHA256)
+	require.NoError(t, err)
+	assert.NoError(t, d.Verify(bytes.NewReader([]byte("new"))))
+
+	require.Error(t, WriteAtomic(filepath.Join(dir, "missing", "file"), nil, 0o644))
+}
+
+func TestTarUntar(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"a/b":       "hello",
+		"a.txt":     "world",
+		"cache/tmp": "skipped",
+	})
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Symlink("a.txt", filepath.Join(root, "link")))
+	}
+	var events []progress.Event
+	reporter := WithProgress(progress.ReporterFunc(func(e progress.Event) {
+		if e.Done {
+			events = append(events, e)
+		}
+	}))
+	var buf bytes.Buffer
+	require.NoError(t, Tar(&buf, root, WithExcludes("cache"), reporter))
+
+	dest := filepath.Join(t.TempDir(), "dest")
+	require.NoError(t, Untar(bytes.NewReader(buf.Bytes()), dest, reporter))
+	// both report the bytes of the file contents when done
+	require.Len(t, events, 2)
+	assert.Equal(t, progress.Event{Operation: "tar", Path: root, BytesDone: 10, Done: true}, withoutRate(events[0]))
+	assert.Equal(t, progress.Event{Operation: "untar", Path: dest, BytesDone: 10, Done: true}, withoutRate(events[1]))
+	want, err := CreateManifest(root, WithExcludes("cache"))
+	require.NoError(t, err)
+	report, err := VerifyManifest(dest, want)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), report.String())
+
+	err = Untar(bytes.NewReader(buf.Bytes()), t.TempDir(), WithMaxSize(9))
+	require.ErrorIs(t, err, ErrLimitExceeded)
+	err = Untar(bytes.NewReader(buf.Bytes()), t.TempDir(), WithMaxEntries(2))
+	require.ErrorIs(t, err, ErrLimitExceeded)
+}
+
+func withoutRate(e progress.Event) progress.Event {
+	e.Rate = 0
+	return e
+}
+
+func TestUntarUnsafe(t *testing.T) {
+	archive := func(headers ...*tar.Header) *bytes.Reader {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, h := range headers {
+			require.NoError(t, tw.WriteHeader(h))
+			if h.Size > 0 {
+				_, err := tw.Write(make([]byte, h.Size))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, tw.Close())
+		return bytes.NewReader(buf.Bytes())
+	}
+	for name, r := range map[string]*bytes.Reader{
+		"traversal":        archive(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1}),
+		"nested traversal": archive(&tar.Header{Name: "a/../../evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1}),
+		"absolute":         archive(&tar.Header{Name: "/etc/evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1}),
+		"absolute symlink": archive(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}),
+		"escaping symlink": archive(&tar.Header{Name: "a/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"}),
+		"write through symlink": archive(
+			&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "."},
+			&tar.Header{Name: "link/evil", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		),
+		"escape through extracted symlinks": archive(
+			&tar.Header{Name: "a/", Typeflag: tar.TypeDir, Mode: 0o755},
+			&tar.Header{Name: "a/b", Typeflag: tar.TypeSymlink, Linkname: ".."},
+			&tar.Header{Name: "s", Typeflag: tar.TypeSymlink, Linkname: "a/b"},
+			&tar.Header{Name: "d", Typeflag: tar.TypeSymlink, Linkname: "s/.."},
+		),
+		"escape through later symlink": archive(
+			&tar.Header{Name: "y", Typeflag: tar.TypeSymlink, Linkname: "x/.."},
+			&tar.Header{Name: "x", Typeflag: tar.TypeSymlink, Linkname: "."},
+		),
+		"hard link outside": archive(&tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "../secret"}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := Untar(r, filepath.Join(t.TempDir(), "dest"))
+			require.ErrorIs(t, err, ErrUnsafePath)
+		})
+	}
+
+	err := Untar(archive(&tar.Header{Name: "fifo", Typeflag: tar.TypeFifo}), t.TempDir())
+	require.EqualError(t, err, `unsupported type '6' of fifo in archive`)
+}
@@ -0,0 +1,169 @@
+package file
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/argoproj/pkg/v2/digest"
+	"github.com/argoproj/pkg/v2/progress"
+)
+
+// Entry describes a file, directory or symlink of a tree
+type Entry struct {
+	// Path is relative to the root of the tree and always uses forward slashes
+	Path string      `json:"path"`
+	Mode fs.FileMode `json:"mode"`
+	// Size and Digest are only set for regular files
+	Size   int64          `json:"size,omitempty"`
+	Digest *digest.Digest `json:"digest,omitempty"`
+	// Link is the target of a symlink. Symlinks are not followed.
+	Link string `json:"link,omitempty"`
+}
+
+// Manifest describes the contents of a directory tree
+type Manifest struct {
+	// Entries are sorted by path
+	Entries []Entry `json:"entries"`
+}
+
+// Report is the result of verifying a tree against a manifest. Each list holds sorted paths.
+type Report struct {
+	// Added are entries of the tree which are not in the manifest
+	Added []string `json:"added,omitempty"`
+	// Missing are entries of the manifest which are not in the tree
+	Missing []string `json:"missing,omitempty"`
+	// Modified are entries whose type, mode, size, content or link target differ
+	Modified []string `json:"modified,omitempty"`
+}
+
+// OK returns true if the tree matches the manifest
+func (r *Report) OK() bool {
+	return len(r.Added) == 0 && len(r.Missing) == 0 && len(r.Modified) == 0
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("%d added, %d missing, %d modified", len(r.Added), len(r.Missing), len(r.Modified))
+}
+
+// CreateManifest walks the tree rooted at root and returns its manifest. The root itself is not
+// part of the manifest. Digests are computed with sha256 unless WithAlgorithm is given. WithMaxSize
+// and WithMaxEntries are ignored.
+func CreateManifest(root string, opts ...Option) (*Manifest, error) {
+	o := newOptions(opts)
+	alg := o.algorithm
+	if !alg.Available() {
+		return nil, fmt.Errorf("unsupported digest algorithm '%s'", alg)
+	}
+	m := &Manifest{}
+	tracker := progress.NewTracker(o.reporter, "manifest", root, 0)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if o.excluded(filepath.ToSlash(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := Entry{Path: filepath.ToSlash(rel), Mode: info.Mode()}
+		switch {
+		case info.Mode().IsRegular():
+			entry.Size = info.Size()
+			d, err := digestFile(alg, path, tracker)
+			if err != nil {
+				return err
+			}
+			entry.Digest = &d
+		case info.Mode()&fs.ModeSymlink != 0:
+			if entry.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		m.Entries = append(m.Entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	tracker.Finish()
+	// WalkDir visits entries in lexical order of their names, which is not the lexical order of the
+	// paths, e.g. "a/b" is visited before "a.txt"
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	return m, nil
+}
+
+// VerifyManifest compares the tree rooted at root against the manifest. The digest algorithm of the
+// manifest is always used, regardless of WithAlgorithm. Entries of the manifest matching
+// WithExcludes are ignored as well. WithMaxSize and WithMaxEntries are ignored.
+func VerifyManifest(root string, m *Manifest, opts ...Option) (*Report, error) {
+	o := newOptions(opts)
+	actual, err := CreateManifest(root, append(opts, WithAlgorithm(m.algorithm()))...)
+	if err != nil {
+		return nil, err
+	}
+	expected := make(map[string]Entry, len(m.Entries))
+	for _, e := range m.Entries {
+		if !o.excluded(e.Path) {
+			expected[e.Path] = e
+		}
+	}
+	r := &Report{}
+	for _, e := range actual.Entries {
+		want, ok := expected[e.Path]
+		delete(expected, e.Path)
+		switch {
+		case !ok:
+			r.Added = append(r.Added, e.Path)
+		case !want.matches(e):
+			r.Modified = append(r.Modified, e.Path)
+		}
+	}
+	for path := range expected {
+		r.Missing = append(r.Missing, path)
+	}
+	sort.Strings(r.Missing)
+	return r, nil
+}
+
+func digestFile(alg digest.Algorithm, path string, tracker *progress.Tracker) (digest.Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return digest.Digest{}, err
+	}
+	defer f.Close()
+	return digest.FromReader(alg, tracker.Reader(f))
+}
+
+// algorithm returns the digest algorithm used by the manifest
+func (m *Manifest) algorithm() digest.Algorithm {
+	for _, e := range m.Entries {
+		if e.Digest != nil {
+			return e.Digest.Algorithm
+		}
+	}
+	return digest.DefaultAlgorithm
+}
+
+// matches returns true if the actual entry e matches the expected entry
+func (want Entry) matches(e Entry) bool {
+	if (want.Digest == nil) != (e.Digest == nil) || (want.Digest != nil && *want.Digest != *e.Digest) {
+		return false
+	}
+	want.Digest = e.Digest
+	return want == e
+}
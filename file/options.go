@@ -0,0 +1,86 @@
+package file
+
+import (
+	"path"
+
+	"github.com/argoproj/pkg/v2/digest"
+	"github.com/argoproj/pkg/v2/progress"
+)
+
+// Option configures the functions of this package. The zero value of every setting is a sensible
+// default, so new options never change the behavior of existing callers.
+type Option func(*options)
+
+type options struct {
+	algorithm digest.Algorithm
+	excludes  []string
+	reporter  progress.Reporter
+	// maxSize and maxEntries limit extracted archives, zero means unlimited
+	maxSize    int64
+	maxEntries int
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{algorithm: digest.DefaultAlgorithm}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithAlgorithm sets the digest algorithm of CreateManifest. Defaults to digest.DefaultAlgorithm.
+// Tar and Untar ignore it.
+func WithAlgorithm(alg digest.Algorithm) Option {
+	return func(o *options) {
+		o.algorithm = alg
+	}
+}
+
+// WithExcludes skips files and directories matching any of the patterns. Patterns use the syntax
+// of path.Match and are matched against both the slash separated path relative to the root and
+// the base name, so "*.tmp" excludes temporary files at any depth and "cache/*" the contents of a
+// top level directory. Excluding a directory excludes everything below it.
+func WithExcludes(patterns ...string) Option {
+	return func(o *options) {
+		o.excludes = append(o.excludes, patterns...)
+	}
+}
+
+// WithProgress reports the progress of long running operations, such as hashing the files of a
+// manifest or writing and extracting archives, to reporter
+func WithProgress(reporter progress.Reporter) Option {
+	return func(o *options) {
+		o.reporter = reporter
+	}
+}
+
+// WithMaxSize limits the total size of the files extracted by Untar. Other functions ignore it.
+func WithMaxSize(bytes int64) Option {
+	return func(o *options) {
+		o.maxSize = bytes
+	}
+}
+
+// WithMaxEntries limits the number of files, directories and links extracted by Untar. Other
+// functions ignore it.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// excluded returns true if the slash separated relative path or one of its parents matches an
+// exclude pattern
+func (o *options) excluded(rel string) bool {
+	for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+		for _, pattern := range o.excludes {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, path.Base(p)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+package file
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/argoproj/pkg/v2/progress"
+)
+
+var (
+	// ErrUnsafePath is returned by Untar for entries which would be written outside of the
+	// destination, either directly or through a symlink
+	ErrUnsafePath = errors.New("unsafe path in archive")
+	// ErrLimitExceeded is returned by Untar when the archive exceeds WithMaxSize or WithMaxEntries
+	ErrLimitExceeded = errors.New("archive exceeds limit")
+)
+
+// Tar writes the tree rooted at root to w as an uncompressed tar archive, with paths relative to
+// root. Symlinks are stored as links and not followed. WithExcludes skips matching entries and
+// WithProgress reports the bytes of file contents written. Other options are ignored.
+func Tar(w io.Writer, root string, opts ...Option) error {
+	o := newOptions(opts)
+	tracker := progress.NewTracker(o.reporter, "tar", root, 0)
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if o.excluded(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, tracker.Reader(f))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	tracker.Finish()
+	return nil
+}
+
+// Untar extracts the tar archive read from r into dest, which is created if needed. It rejects
+// entries with absolute paths or paths leaving dest, symlinks pointing outside of dest, also when
+// resolved through previously extracted symlinks, and entries which would be written through a
+// symlink. Only permission bits of modes are kept.
+// Devices, pipes and other special files are rejected. If an error is returned, dest may contain
+// the entries extracted so far. WithMaxSize, WithMaxEntries and WithProgress apply, other options
+// are ignored.
+func Untar(r io.Reader, dest string, opts ...Option) error {
+	o := newOptions(opts)
+	tracker := progress.NewTracker(o.reporter, "untar", dest, 0)
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	var size int64
+	entries := 0
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			tracker.Finish()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		entries++
+		if o.maxEntries > 0 && entries > o.maxEntries {
+			return fmt.Errorf("%w: more than %d entries", ErrLimitExceeded, o.maxEntries)
+		}
+		name, err := safeName(header.Name)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			continue
+		}
+		target := filepath.Join(dest, filepath.FromSlash(name))
+		if err := makeParents(dest, name); err != nil {
+			return err
+		}
+		mode := fs.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if info, err := os.Lstat(target); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+				return fmt.Errorf("%w: %s is a symlink", ErrUnsafePath, header.Name)
+			}
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode|0o700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if o.maxSize > 0 && size+header.Size > o.maxSize {
+				return fmt.Errorf("%w: more than %d bytes", ErrLimitExceeded, o.maxSize)
+			}
+			n, err := extractFile(tracker.Reader(tr), target, mode)
+			size += n
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := checkLinkTarget(dest, name, header.Linkname); err != nil {
+				return err
+			}
+			if err := replace(target); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			linkName, err := safeName(header.Linkname)
+			if err != nil {
+				return err
+			}
+			if err := makeParents(dest, linkName); err != nil {
+				return err
+			}
+			source := filepath.Join(dest, filepath.FromSlash(linkName))
+			if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+				return fmt.Errorf("%w: %s is a hard link to %s, which is not an extracted file", ErrUnsafePath, header.Name, header.Linkname)
+			}
+			if err := replace(target); err != nil {
+				return err
+			}
+			if err := os.Link(source, target); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported type %q of %s in archive", header.Typeflag, header.Name)
+		}
+	}
+}
+
+// safeName returns the cleaned slash separated name of an entry, or ErrUnsafePath if it is absolute
+// or leaves the destination
+func safeName(name string) (string, error) {
+	clean := path.Clean(strings.ReplaceAll(name, `\`, "/"))
+	if path.IsAbs(clean) || filepath.IsAbs(name) || !isLocal(clean) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return clean, nil
+}
+
+// isLocal returns true if the cleaned slash separated path stays below its root
+func isLocal(p string) bool {
+	p = path.Clean(p)
+	return p != ".." && !strings.HasPrefix(p, "../") && !path.IsAbs(p)
+}
+
+// checkLinkTarget returns ErrUnsafePath unless the target of the symlink entry stays inside dest.
+// The target is resolved component by component against what was extracted so far: every
+// component but the last must be an existing directory, not a symlink. Otherwise a ".." after a
+// symlink, or after a path which a later entry turns into a symlink, could lead outside of dest.
+// The last component may be a symlink, whose own target was checked when it was extracted.
+func checkLinkTarget(dest, name, linkname string) error {
+	unsafe := fmt.Errorf("%w: %s links to %s", ErrUnsafePath, name, linkname)
+	target := filepath.ToSlash(linkname)
+	if path.IsAbs(target) || filepath.IsAbs(linkname) {
+		return unsafe
+	}
+	// the parents of the link were checked by makeParents
+	var dir []string
+	if parent := path.Dir(name); parent != "." {
+		dir = strings.Split(parent, "/")
+	}
+	parts := strings.Split(target, "/")
+	for i, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(dir) == 0 {
+				return unsafe
+			}
+			dir = dir[:len(dir)-1]
+			continue
+		}
+		dir = append(dir, part)
+		if i == len(parts)-1 {
+			break
+		}
+		info, err := os.Lstat(filepath.Join(dest, filepath.FromSlash(strings.Join(dir, "/"))))
+		if err != nil || !info.IsDir() {
+			return unsafe
+		}
+	}
+	return nil
+}
+
+// makeParents creates the parent directories of the entry. It returns ErrUnsafePath if an existing
+// parent is a symlink, which a malicious archive could have extracted to redirect later entries
+// outside of dest.
+func makeParents(dest, name string) error {
+	dir := dest
+	parts := strings.Split(name, "/")
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return os.MkdirAll(filepath.Join(dest, filepath.FromSlash(path.Dir(name))), 0o755)
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %s is below the symlink %s", ErrUnsafePath, name, part)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is below %s, which is not a directory", name, part)
+		}
+	}
+	return nil
+}
+
+// replace removes an existing file or link at target, so that it is replaced instead of written
+// through
+func replace(target string) error {
+	info, err := os.Lstat(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s already exists as a directory", target)
+	}
+	return os.Remove(target)
+}
+
+func extractFile(r io.Reader, target string, mode fs.FileMode) (int64, error) {
+	if err := replace(target); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
@@ -0,0 +1,38 @@
+package ioutil
+
+import (
+	"context"
+	"io"
+)
+
+const copyBufferSize = 32 * 1024
+
+// Copy copies from src to dst until EOF, an error, or the context is done, and returns the number
+// of bytes copied. The context is checked between chunks, so a Read or Write that blocks forever
+// is not interrupted; use deadlines on the underlying connection for that.
+func Copy(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	buf := make([]byte, copyBufferSize)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		nr, rerr := src.Read(buf)
+		if nr > 0 {
+			nw, werr := dst.Write(buf[:nr])
+			written += int64(nw)
+			if werr != nil {
+				return written, werr
+			}
+			if nw != nr {
+				return written, io.ErrShortWrite
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
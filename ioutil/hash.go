@@ -0,0 +1,62 @@
+package ioutil
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// HashingReader computes a hash of the bytes read through it
+type HashingReader struct {
+	r io.Reader
+	h hash.Hash
+}
+
+// NewHashingReader returns a HashingReader that reads from r and feeds h
+func NewHashingReader(r io.Reader, h hash.Hash) *HashingReader {
+	return &HashingReader{r: r, h: h}
+}
+
+func (r *HashingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	// hash.Hash.Write never returns an error
+	_, _ = r.h.Write(p[:n])
+	return n, err
+}
+
+// Sum returns the hash of the bytes read so far
+func (r *HashingReader) Sum() []byte {
+	return r.h.Sum(nil)
+}
+
+// HexSum returns the hex encoded hash of the bytes read so far
+func (r *HashingReader) HexSum() string {
+	return hex.EncodeToString(r.Sum())
+}
+
+// HashingWriter computes a hash of the bytes written through it
+type HashingWriter struct {
+	w io.Writer
+	h hash.Hash
+}
+
+// NewHashingWriter returns a HashingWriter that writes to w and feeds h
+func NewHashingWriter(w io.Writer, h hash.Hash) *HashingWriter {
+	return &HashingWriter{w: w, h: h}
+}
+
+func (w *HashingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	_, _ = w.h.Write(p[:n])
+	return n, err
+}
+
+// Sum returns the hash of the bytes written so far
+func (w *HashingWriter) Sum() []byte {
+	return w.h.Sum(nil)
+}
+
+// HexSum returns the hex encoded hash of the bytes written so far
+func (w *HashingWriter) HexSum() string {
+	return hex.EncodeToString(w.Sum())
+}
@@ -0,0 +1,116 @@
+package ioutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounting(t *testing.T) {
+	r := NewCountingReader(strings.NewReader("hello world"))
+	var buf bytes.Buffer
+	w := NewCountingWriter(&buf)
+	n, err := io.Copy(w, r)
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), n)
+	assert.Equal(t, int64(11), r.Count())
+	assert.Equal(t, int64(11), w.Count())
+	assert.Equal(t, "hello world", buf.String())
+}
+
+func TestHashing(t *testing.T) {
+	expected := sha256.Sum256([]byte("hello world"))
+	r := NewHashingReader(strings.NewReader("hello world"), sha256.New())
+	w := NewHashingWriter(io.Discard, sha256.New())
+	_, err := io.Copy(w, r)
+	require.NoError(t, err)
+	assert.Equal(t, expected[:], r.Sum())
+	assert.Equal(t, expected[:], w.Sum())
+	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", r.HexSum())
+}
+
+func TestRateLimited(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 3000)
+	start := time.Now()
+	var buf bytes.Buffer
+	// the first 1000 bytes are the burst, the remaining 2000 take 2s at 1000 bytes per second
+	r := NewRateLimitedReader(context.Background(), bytes.NewReader(data[:1500]), 1000)
+	w := NewRateLimitedWriter(context.Background(), &buf, 1000)
+	_, err := io.Copy(w, io.MultiReader(r, bytes.NewReader(data[1500:])))
+	require.NoError(t, err)
+	assert.Equal(t, data, buf.Bytes())
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = io.ReadAll(NewRateLimitedReader(ctx, bytes.NewReader(data), 1000))
+	require.ErrorIs(t, err, context.Canceled)
+
+	unlimited := strings.NewReader("x")
+	assert.Equal(t, unlimited, NewRateLimitedReader(context.Background(), unlimited, 0))
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestCopy(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := Copy(context.Background(), &buf, strings.NewReader(strings.Repeat("x", 100_000)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(100_000), n)
+	assert.Equal(t, 100_000, buf.Len())
+
+	_, err = Copy(context.Background(), errWriter{}, strings.NewReader("x"))
+	require.EqualError(t, err, "disk full")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	n, err = Copy(ctx, &buf, strings.NewReader("x"))
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestSpillBuffer(t *testing.T) {
+	dir := t.TempDir()
+	s := NewSpillBuffer(10, dir)
+	_, err := s.Write([]byte("hello"))
+	require.NoError(t, err)
+	assert.False(t, s.Spilled())
+	inMemory := s.Reader()
+
+	_, err = s.Write([]byte(" world, this is long"))
+	require.NoError(t, err)
+	assert.True(t, s.Spilled())
+	assert.Equal(t, int64(25), s.Len())
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	data, err := io.ReadAll(inMemory)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	r := s.Reader()
+	_, err = r.Seek(6, io.SeekStart)
+	require.NoError(t, err)
+	data, err = io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "world, this is long", string(data))
+
+	require.NoError(t, s.Close())
+	files, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	require.NoError(t, s.Close())
+}
@@ -0,0 +1,76 @@
+package ioutil
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitChunk is the largest number of bytes transferred per call, which bounds both the burst
+// and how long a single call blocks
+const rateLimitChunk = 32 * 1024
+
+// NewRateLimitedReader returns a reader that reads from r at no more than bytesPerSecond. Reads
+// block until enough budget is available, and fail with the context error once ctx is done. A
+// non-positive rate disables limiting.
+func NewRateLimitedReader(ctx context.Context, r io.Reader, bytesPerSecond int) io.Reader {
+	if bytesPerSecond <= 0 {
+		return r
+	}
+	return &rateLimitedReader{ctx: ctx, r: r, limiter: newLimiter(bytesPerSecond)}
+}
+
+// NewRateLimitedWriter returns a writer that writes to w at no more than bytesPerSecond
+func NewRateLimitedWriter(ctx context.Context, w io.Writer, bytesPerSecond int) io.Writer {
+	if bytesPerSecond <= 0 {
+		return w
+	}
+	return &rateLimitedWriter{ctx: ctx, w: w, limiter: newLimiter(bytesPerSecond)}
+}
+
+func newLimiter(bytesPerSecond int) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), max(1, min(bytesPerSecond, rateLimitChunk)))
+}
+
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > l.limiter.Burst() {
+		p = p[:l.limiter.Burst()]
+	}
+	n, err := l.r.Read(p)
+	if n > 0 {
+		if werr := l.limiter.WaitN(l.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type rateLimitedWriter struct {
+	ctx     context.Context
+	w       io.Writer
+	limiter *rate.Limiter
+}
+
+func (l *rateLimitedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		chunk := p[:min(len(p), l.limiter.Burst())]
+		if err := l.limiter.WaitN(l.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := l.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
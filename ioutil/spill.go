@@ -0,0 +1,97 @@
+package ioutil
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// SpillBuffer keeps written data in memory up to a threshold, and moves it to a temporary file
+// once the threshold is exceeded. This bounds the memory used for data of unexpected size, such
+// as command output, without truncating it. It is safe for concurrent use. Close must be called
+// to remove the temporary file.
+type SpillBuffer struct {
+	threshold int
+	dir       string
+
+	lock sync.Mutex
+	buf  bytes.Buffer
+	file *os.File
+	size int64
+}
+
+// NewSpillBuffer returns a SpillBuffer keeping up to threshold bytes in memory. The temporary file
+// is created in dir, or the default temporary directory if dir is empty.
+func NewSpillBuffer(threshold int, dir string) *SpillBuffer {
+	return &SpillBuffer{threshold: threshold, dir: dir}
+}
+
+func (s *SpillBuffer) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil && s.buf.Len()+len(p) > s.threshold {
+		f, err := os.CreateTemp(s.dir, "spill-")
+		if err != nil {
+			return 0, err
+		}
+		if _, err := f.Write(s.buf.Bytes()); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return 0, err
+		}
+		s.file = f
+		s.buf = bytes.Buffer{}
+	}
+	if s.file == nil {
+		n, _ := s.buf.Write(p)
+		s.size += int64(n)
+		return n, nil
+	}
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+// Len returns the number of bytes written
+func (s *SpillBuffer) Len() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.size
+}
+
+// Spilled returns true if the data was moved to a temporary file
+func (s *SpillBuffer) Spilled() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.file != nil
+}
+
+// Reader returns a reader over the data written so far. It stays valid until Close is called, and
+// does not see later writes.
+func (s *SpillBuffer) Reader() io.ReadSeeker {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return bytes.NewReader(append([]byte(nil), s.buf.Bytes()...))
+	}
+	return io.NewSectionReader(s.file, 0, s.size)
+}
+
+// Close removes the temporary file, if any
+func (s *SpillBuffer) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	f := s.file
+	s.file = nil
+	s.buf = bytes.Buffer{}
+	s.size = 0
+	err := f.Close()
+	if rmErr := os.Remove(f.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
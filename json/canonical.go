@@ -0,0 +1,64 @@
+package json
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+)
+
+// Canonicalize returns the JSON encoding of v with object keys sorted, no insignificant whitespace
+// and no HTML escaping, so that equal values produce equal bytes, e.g. for hashing specs. Values
+// which are already JSON documents ([]byte or json.RawMessage) are re-encoded and must hold a
+// single value. Numbers are kept as written, so 1 and 1.0 are not considered equal.
+func Canonicalize(v any) ([]byte, error) {
+	data := []byte(`null`)
+	if v != nil {
+		var err error
+		if data, err = marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return nil, errors.New("invalid JSON: unexpected data after top-level value")
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	// maps are encoded with sorted keys
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// MergePatch applies the RFC 7386 JSON merge patch to the original document
+func MergePatch(original, patch []byte) ([]byte, error) {
+	return jsonpatch.MergePatch(original, patch)
+}
+
+// Diff returns the canonical JSON merge patch which transforms a into b, or nil if they are
+// structurally equal. Unlike CreateMergePatch, the key order and formatting of the inputs do not
+// affect the result.
+func Diff(a, b any) ([]byte, error) {
+	patch, err := CreateMergePatch(a, b)
+	if err != nil {
+		return nil, err
+	}
+	canonical, err := Canonicalize(json.RawMessage(patch))
+	if err != nil {
+		return nil, err
+	}
+	if string(canonical) == `{}` {
+		return nil, nil
+	}
+	return canonical, nil
+}
@@ -0,0 +1,34 @@
+package json
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	a, err := Canonicalize([]byte(`{ "b": [1, 2.50, {"d": "<x>", "c": null}], "a": true }`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":true,"b":[1,2.50,{"c":null,"d":"<x>"}]}`, string(a))
+
+	b, err := Canonicalize(map[string]any{"a": true, "b": []any{1, json.Number("2.50"), map[string]any{"c": nil, "d": "<x>"}}})
+	require.NoError(t, err)
+	assert.Equal(t, string(a), string(b))
+
+	s, err := Canonicalize(spec{Replicas: 1, Labels: map[string]string{"z": "1", "a": "2"}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"labels":{"a":"2","z":"1"},"replicas":1}`, string(s))
+
+	n, err := Canonicalize(nil)
+	require.NoError(t, err)
+	assert.Equal(t, `null`, string(n))
+
+	_, err = Canonicalize([]byte(`{`))
+	require.Error(t, err)
+	_, err = Canonicalize([]byte(`{} garbage`))
+	require.Error(t, err)
+	_, err = Canonicalize(json.RawMessage(`{} {}`))
+	require.Error(t, err)
+}
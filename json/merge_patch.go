@@ -0,0 +1,57 @@
+package json
+
+import (
+	"encoding/json"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+)
+
+// CreateMergePatch marshals both objects and returns the RFC 7386 JSON merge patch which transforms
+// original into modified. An empty object ("{}") is returned when there is no difference.
+func CreateMergePatch(original, modified any) ([]byte, error) {
+	originalJSON, err := marshal(original)
+	if err != nil {
+		return nil, err
+	}
+	modifiedJSON, err := marshal(modified)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpatch.CreateMergePatch(originalJSON, modifiedJSON)
+}
+
+// CreateThreeWayMergePatch returns a JSON merge patch which transforms current into modified, while
+// only deleting the fields which were removed since lastApplied. Fields which were set on current
+// by other actors, and which were never part of lastApplied, are left untouched. A nil lastApplied
+// means nothing was applied before. A conflict error is returned if the changes since lastApplied
+// contradict the changes made on current.
+func CreateThreeWayMergePatch(lastApplied, modified, current any) ([]byte, error) {
+	lastAppliedJSON, err := marshal(lastApplied)
+	if err != nil {
+		return nil, err
+	}
+	modifiedJSON, err := marshal(modified)
+	if err != nil {
+		return nil, err
+	}
+	currentJSON, err := marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	return jsonmergepatch.CreateThreeWayJSONMergePatch(lastAppliedJSON, modifiedJSON, currentJSON)
+}
+
+// marshal encodes v, passing through values which are already JSON documents
+func marshal(v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return []byte(`{}`), nil
+	case []byte:
+		return v, nil
+	case json.RawMessage:
+		return v, nil
+	default:
+		return json.Marshal(v)
+	}
+}
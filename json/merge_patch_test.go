@@ -0,0 +1,67 @@
+package json
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type spec struct {
+	Replicas int               `json:"replicas,omitempty"`
+	Image    string            `json:"image,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	original := spec{Replicas: 1, Image: "nginx:1", Labels: map[string]string{"a": "1", "b": "2"}}
+	modified := spec{Replicas: 3, Image: "nginx:1", Labels: map[string]string{"a": "1"}}
+	patch, err := CreateMergePatch(original, modified)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"replicas":3,"labels":{"b":null}}`, string(patch))
+
+	patch, err = CreateMergePatch(original, original)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(patch))
+
+	patch, err = CreateMergePatch([]byte(`{"a":1}`), map[string]int{"a": 2})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":2}`, string(patch))
+
+	_, err = CreateMergePatch(func() {}, original)
+	require.Error(t, err)
+}
+
+func TestCreateThreeWayMergePatch(t *testing.T) {
+	lastApplied := spec{Replicas: 1, Labels: map[string]string{"owned": "1"}}
+	modified := spec{Replicas: 2, Labels: map[string]string{"new": "1"}}
+	// another controller added a label which must be preserved
+	current := spec{Replicas: 1, Labels: map[string]string{"owned": "1", "foreign": "x"}}
+
+	patch, err := CreateThreeWayMergePatch(lastApplied, modified, current)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"replicas":2,"labels":{"owned":null,"new":"1"}}`, string(patch))
+
+	// nothing applied before, so nothing is deleted
+	patch, err = CreateThreeWayMergePatch(nil, modified, current)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"replicas":2,"labels":{"new":"1"}}`, string(patch))
+}
+
+func TestMergePatchAndDiff(t *testing.T) {
+	original := []byte(`{"replicas":1,"labels":{"a":"1","b":"2"}}`)
+	patch, err := Diff(original, []byte(`{"labels":{"a":"1"},"replicas":3,"image":"nginx"}`))
+	require.NoError(t, err)
+	assert.Equal(t, `{"image":"nginx","labels":{"b":null},"replicas":3}`, string(patch))
+
+	patched, err := MergePatch(original, patch)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"replicas":3,"image":"nginx","labels":{"a":"1"}}`, string(patched))
+
+	patch, err = Diff(original, []byte(`{ "labels": {"b": "2", "a": "1"}, "replicas": 1 }`))
+	require.NoError(t, err)
+	assert.Nil(t, patch)
+
+	_, err = MergePatch(original, []byte(`{`))
+	require.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// Reasons shared by controllers, so that users can filter events consistently
+const (
+	ReasonCreated      = "Created"
+	ReasonUpdated      = "Updated"
+	ReasonDeleted      = "Deleted"
+	ReasonSynced       = "Synced"
+	ReasonFailedCreate = "FailedCreate"
+	ReasonFailedUpdate = "FailedUpdate"
+	ReasonFailedDelete = "FailedDelete"
+	ReasonFailedSync   = "FailedSync"
+	ReasonInvalidSpec  = "InvalidSpec"
+)
+
+const (
+	DefaultDedupWindow = 5 * time.Minute
+	DefaultQPS         = 1.0 / 10
+	DefaultBurst       = 5
+)
+
+// Options configures a Recorder. Zero values are replaced by the defaults above.
+type Options struct {
+	// DedupWindow is how long an identical event for the same object is suppressed
+	DedupWindow time.Duration
+	// QPS and Burst limit the rate of events per object
+	QPS   float64
+	Burst int
+}
+
+// Recorder wraps a record.EventRecorder, dropping identical events for the same object within the
+// dedup window and limiting the rate of events per object. Dropped events are logged at debug
+// level.
+type Recorder struct {
+	recorder record.EventRecorder
+	opts     Options
+	now      func() time.Time
+
+	lock      sync.Mutex
+	objects   map[string]*objectState
+	lastPrune time.Time
+}
+
+type objectState struct {
+	limiter *rate.Limiter
+	// emitted maps type, reason and message to the time the event was last emitted
+	emitted map[string]time.Time
+}
+
+// NewRecorder returns a Recorder emitting events through recorder
+func NewRecorder(recorder record.EventRecorder, opts Options) *Recorder {
+	if opts.DedupWindow == 0 {
+		opts.DedupWindow = DefaultDedupWindow
+	}
+	if opts.QPS == 0 {
+		opts.QPS = DefaultQPS
+	}
+	if opts.Burst == 0 {
+		opts.Burst = DefaultBurst
+	}
+	return &Recorder{recorder: recorder, opts: opts, now: time.Now, objects: map[string]*objectState{}}
+}
+
+// NewBroadcastRecorder returns a record.EventRecorder which writes events for the given component
+// to the Kubernetes API, and a function which stops the underlying broadcaster
+func NewBroadcastRecorder(ctx context.Context, client kubernetes.Interface, component string) (record.EventRecorder, func()) {
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: component}), broadcaster.Shutdown
+}
+
+// Normal records an event of type Normal
+func (r *Recorder) Normal(ctx context.Context, obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	r.Event(ctx, obj, corev1.EventTypeNormal, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// Warning records an event of type Warning
+func (r *Recorder) Warning(ctx context.Context, obj runtime.Object, reason, messageFmt string, args ...interface{}) {
+	r.Event(ctx, obj, corev1.EventTypeWarning, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+// Event records an event unless it is a duplicate or the object exceeded its rate limit
+func (r *Recorder) Event(ctx context.Context, obj runtime.Object, eventType, reason, message string) {
+	key, err := objectKey(obj)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("failed to record event %s: %v", reason, err)
+		return
+	}
+	if dropped := r.admit(key, eventType+"/"+reason+"/"+message); dropped != "" {
+		logging.FromContext(ctx).WithFields(logging.Fields{"object": key, "reason": reason}).Debugf("Dropped %s event: %s", dropped, message)
+		return
+	}
+	r.recorder.Event(obj, eventType, reason, message)
+}
+
+// admit returns why the event should be dropped, or an empty string if it should be emitted
+func (r *Recorder) admit(key, event string) string {
+	now := r.now()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.prune(now)
+	state, ok := r.objects[key]
+	if !ok {
+		state = &objectState{
+			limiter: rate.NewLimiter(rate.Limit(r.opts.QPS), r.opts.Burst),
+			emitted: map[string]time.Time{},
+		}
+		r.objects[key] = state
+	}
+	if last, ok := state.emitted[event]; ok && now.Sub(last) < r.opts.DedupWindow {
+		return "duplicate"
+	}
+	if !state.limiter.AllowN(now, 1) {
+		return "rate limited"
+	}
+	state.emitted[event] = now
+	return ""
+}
+
+// prune forgets emitted events older than the dedup window, and objects without recent events
+// whose rate limiter has refilled. It does so at most once per dedup window.
+func (r *Recorder) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < r.opts.DedupWindow {
+		return
+	}
+	r.lastPrune = now
+	for key, state := range r.objects {
+		for event, last := range state.emitted {
+			if now.Sub(last) >= r.opts.DedupWindow {
+				delete(state.emitted, event)
+			}
+		}
+		if len(state.emitted) == 0 && state.limiter.TokensAt(now) >= float64(r.opts.Burst) {
+			delete(r.objects, key)
+		}
+	}
+}
+
+// objectKey identifies the object by UID, which distinguishes re-created objects of the same name
+func objectKey(obj runtime.Object) (string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", err
+	}
+	if uid := accessor.GetUID(); uid != "" {
+		return string(uid), nil
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName(), nil
+}
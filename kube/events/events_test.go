@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func drain(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case e := <-recorder.Events:
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	fake := record.NewFakeRecorder(100)
+	r := NewRecorder(fake, Options{DedupWindow: time.Minute, QPS: 1, Burst: 2})
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "my-pod", Namespace: "default", UID: "1"}}
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "my-pod", Namespace: "default", UID: "2"}}
+
+	r.Warning(ctx, pod, ReasonFailedCreate, "failed: %s", "boom")
+	r.Warning(ctx, pod, ReasonFailedCreate, "failed: %s", "boom")
+	r.Warning(ctx, other, ReasonFailedCreate, "failed: %s", "boom")
+	r.Normal(ctx, pod, ReasonSynced, "synced")
+	r.Normal(ctx, pod, ReasonUpdated, "rate limited")
+	assert.Equal(t, []string{
+		"Warning FailedCreate failed: boom",
+		"Warning FailedCreate failed: boom",
+		"Normal Synced synced",
+	}, drain(fake))
+
+	now = now.Add(2 * time.Second)
+	r.Normal(ctx, pod, ReasonUpdated, "refilled")
+	r.Warning(ctx, pod, ReasonFailedCreate, "failed: %s", "boom")
+	assert.Equal(t, []string{"Normal Updated refilled"}, drain(fake))
+
+	now = now.Add(time.Minute)
+	r.Warning(ctx, pod, ReasonFailedCreate, "failed: %s", "boom")
+	assert.Equal(t, []string{"Warning FailedCreate failed: boom"}, drain(fake))
+	// the other pod was idle for a whole window and has been forgotten
+	assert.Len(t, r.objects, 1)
+}
@@ -0,0 +1,185 @@
+package informers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// FactoryOptions configures the informers created by NewFactory
+type FactoryOptions struct {
+	// Namespace restricts the informers to a single namespace. All namespaces are watched if empty.
+	Namespace string
+	// LabelSelector and FieldSelector restrict the objects which are listed and watched
+	LabelSelector string
+	FieldSelector string
+	// Resync is the default resync period of the informers
+	Resync time.Duration
+	// StripManagedFields removes metadata.managedFields from cached objects, which typically halves
+	// the memory used by the cache
+	StripManagedFields bool
+	// Transform is applied to every object before it is stored in the cache, after managed fields
+	// have been stripped
+	Transform cache.TransformFunc
+}
+
+// NewFactory returns a shared informer factory which applies the given filters and transforms
+func NewFactory(client kubernetes.Interface, opts FactoryOptions) informers.SharedInformerFactory {
+	factoryOpts := []informers.SharedInformerOption{
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			if opts.LabelSelector != "" {
+				options.LabelSelector = opts.LabelSelector
+			}
+			if opts.FieldSelector != "" {
+				options.FieldSelector = opts.FieldSelector
+			}
+		}),
+	}
+	if opts.Namespace != "" {
+		factoryOpts = append(factoryOpts, informers.WithNamespace(opts.Namespace))
+	}
+	if transform := NewTransform(opts.StripManagedFields, opts.Transform); transform != nil {
+		factoryOpts = append(factoryOpts, informers.WithTransform(transform))
+	}
+	return informers.NewSharedInformerFactoryWithOptions(client, opts.Resync, factoryOpts...)
+}
+
+// NewTransform returns a transform function which optionally strips managed fields before applying
+// the given transform. It returns nil if there is nothing to do. It can be passed to
+// SharedIndexInformer.SetTransform for informers which are not created by NewFactory.
+func NewTransform(stripManagedFields bool, transform cache.TransformFunc) cache.TransformFunc {
+	if !stripManagedFields {
+		return transform
+	}
+	return func(obj interface{}) (interface{}, error) {
+		obj = StripManagedFields(obj)
+		if transform != nil {
+			return transform(obj)
+		}
+		return obj, nil
+	}
+}
+
+// StripManagedFields removes metadata.managedFields from obj if it is a Kubernetes object
+func StripManagedFields(obj interface{}) interface{} {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+	return obj
+}
+
+// WaitForCacheSync waits until all informers have synced, logging which ones are still pending
+// every progressInterval. It returns an error if the timeout expires or the context is cancelled.
+// A zero timeout waits until the context is done.
+func WaitForCacheSync(ctx context.Context, timeout, progressInterval time.Duration, synced map[string]cache.InformerSynced) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if progressInterval <= 0 {
+		progressInterval = 10 * time.Second
+	}
+	start := time.Now()
+	poll := time.NewTicker(100 * time.Millisecond)
+	defer poll.Stop()
+	progress := time.NewTicker(progressInterval)
+	defer progress.Stop()
+
+	for {
+		pending := pendingInformers(synced)
+		if len(pending) == 0 {
+			logging.FromContext(ctx).Infof("Informer caches synced in %v", time.Since(start).Round(time.Millisecond))
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for informer caches to sync: %s: %w", strings.Join(pending, ", "), ctx.Err())
+		case <-progress.C:
+			logging.FromContext(ctx).Infof("Waiting for informer caches to sync (%v elapsed): %s", time.Since(start).Round(time.Second), strings.Join(pending, ", "))
+		case <-poll.C:
+		}
+	}
+}
+
+func pendingInformers(synced map[string]cache.InformerSynced) []string {
+	var pending []string
+	for name, hasSynced := range synced {
+		if !hasSynced() {
+			pending = append(pending, name)
+		}
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// Lister provides typed access to the objects cached by an informer
+type Lister[T any] struct {
+	indexer  cache.Indexer
+	resource schema.GroupResource
+}
+
+// NewLister returns a typed lister for the informer. The resource is used in NotFound errors.
+func NewLister[T any](informer cache.SharedIndexInformer, resource schema.GroupResource) *Lister[T] {
+	return &Lister[T]{indexer: informer.GetIndexer(), resource: resource}
+}
+
+// Get returns the object with the given namespace and name. Use an empty namespace for cluster
+// scoped objects. A NotFound API error is returned if the object is not cached.
+func (l *Lister[T]) Get(namespace, name string) (T, error) {
+	var zero T
+	key := name
+	if namespace != "" {
+		key = namespace + "/" + name
+	}
+	obj, exists, err := l.indexer.GetByKey(key)
+	if err != nil {
+		return zero, err
+	}
+	if !exists {
+		return zero, apierrors.NewNotFound(l.resource, name)
+	}
+	typed, ok := obj.(T)
+	if !ok {
+		return zero, fmt.Errorf("unexpected object of type %T in %s cache", obj, l.resource.String())
+	}
+	return typed, nil
+}
+
+// List returns the cached objects in the namespace which match the selector. Use an empty
+// namespace to list across all namespaces.
+func (l *Lister[T]) List(namespace string, selector labels.Selector) ([]T, error) {
+	if selector == nil {
+		selector = labels.Everything()
+	}
+	var result []T
+	var err error
+	appendFn := func(obj interface{}) {
+		if typed, ok := obj.(T); ok {
+			result = append(result, typed)
+		} else if err == nil {
+			err = fmt.Errorf("unexpected object of type %T in %s cache", obj, l.resource.String())
+		}
+	}
+	if namespace == "" {
+		if listErr := cache.ListAll(l.indexer, selector, appendFn); listErr != nil {
+			return nil, listErr
+		}
+	} else if listErr := cache.ListAllByNamespace(l.indexer, namespace, selector, appendFn); listErr != nil {
+		return nil, listErr
+	}
+	return result, err
+}
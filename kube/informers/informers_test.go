@@ -0,0 +1,100 @@
+package informers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newConfigMap(namespace, name string, lbls map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:     namespace,
+			Name:          name,
+			Labels:        lbls,
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+	}
+}
+
+func TestFactoryAndLister(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		newConfigMap("argo", "a", map[string]string{"app": "argo"}),
+		newConfigMap("argo", "b", map[string]string{"app": "other"}),
+		newConfigMap("default", "c", map[string]string{"app": "argo"}),
+	)
+	factory := NewFactory(client, FactoryOptions{
+		Namespace:          "argo",
+		StripManagedFields: true,
+		Transform: func(obj interface{}) (interface{}, error) {
+			cm := obj.(*corev1.ConfigMap)
+			cm.Annotations = map[string]string{"transformed": "true"}
+			return cm, nil
+		},
+	})
+	informer := factory.Core().V1().ConfigMaps().Informer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	require.NoError(t, WaitForCacheSync(ctx, 5*time.Second, time.Second, map[string]cache.InformerSynced{
+		"configmaps": informer.HasSynced,
+	}))
+
+	lister := NewLister[*corev1.ConfigMap](informer, corev1.Resource("configmaps"))
+	cm, err := lister.Get("argo", "a")
+	require.NoError(t, err)
+	assert.Empty(t, cm.ManagedFields)
+	assert.Equal(t, "true", cm.Annotations["transformed"])
+
+	_, err = lister.Get("default", "c")
+	assert.True(t, apierrors.IsNotFound(err))
+
+	all, err := lister.List("", nil)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	selected, err := lister.List("argo", labels.SelectorFromSet(labels.Set{"app": "argo"}))
+	require.NoError(t, err)
+	require.Len(t, selected, 1)
+	assert.Equal(t, "a", selected[0].Name)
+}
+
+func TestWaitForCacheSyncTimeout(t *testing.T) {
+	err := WaitForCacheSync(context.Background(), 50*time.Millisecond, 10*time.Millisecond, map[string]cache.InformerSynced{
+		"synced": func() bool { return true },
+		"never":  func() bool { return false },
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "never")
+	assert.NotContains(t, err.Error(), "synced,")
+}
+
+func TestLabelSelector(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	factory := NewFactory(client, FactoryOptions{LabelSelector: "app=argo", FieldSelector: "metadata.name=a"})
+	factory.Core().V1().ConfigMaps().Informer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	factory.WaitForCacheSync(ctx.Done())
+
+	var restrictions []string
+	for _, action := range client.Actions() {
+		if list, ok := action.(k8stesting.ListAction); ok {
+			restrictions = append(restrictions, list.GetListRestrictions().Labels.String(), list.GetListRestrictions().Fields.String())
+		}
+	}
+	assert.Equal(t, []string{"app=argo", "metadata.name=a"}, restrictions)
+}
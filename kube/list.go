@@ -0,0 +1,36 @@
+package kube
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/argoproj/pkg/v2/pager"
+)
+
+// ListFetchFunc returns a pager.FetchFunc listing the objects page by page with the label and field
+// selectors of options. All pages are served from the same consistent snapshot; if it expires
+// before the last page is fetched, the API server returns a 410 Gone error.
+func ListFetchFunc(lister cache.Lister, options metav1.ListOptions) pager.FetchFunc[runtime.Object] {
+	return func(_ context.Context, req pager.Request) (pager.Page[runtime.Object], error) {
+		options := options
+		options.Limit = req.Limit
+		options.Continue = req.Continue
+		obj, err := lister.List(options)
+		if err != nil {
+			return pager.Page[runtime.Object]{}, err
+		}
+		listMeta, err := meta.ListAccessor(obj)
+		if err != nil {
+			return pager.Page[runtime.Object]{}, err
+		}
+		items, err := meta.ExtractList(obj)
+		if err != nil {
+			return pager.Page[runtime.Object]{}, err
+		}
+		return pager.Page[runtime.Object]{Items: items, Continue: listMeta.GetContinue()}, nil
+	}
+}
@@ -0,0 +1,38 @@
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/argoproj/pkg/v2/pager"
+)
+
+func TestListFetchFunc(t *testing.T) {
+	var requests []metav1.ListOptions
+	lister := &cache.ListWatch{ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+		requests = append(requests, options)
+		if options.Continue == "" {
+			return &corev1.PodList{ListMeta: metav1.ListMeta{Continue: "next"}, Items: []corev1.Pod{*pod("a", "1"), *pod("b", "1")}}, nil
+		}
+		return &corev1.PodList{Items: []corev1.Pod{*pod("c", "1")}}, nil
+	}}
+
+	var names []string
+	err := pager.Each(context.Background(), ListFetchFunc(lister, metav1.ListOptions{LabelSelector: "app=argo"}), func(_ context.Context, obj runtime.Object) error {
+		names = append(names, obj.(*corev1.Pod).Name)
+		return nil
+	}, pager.Options{PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+	assert.Equal(t, []metav1.ListOptions{
+		{LabelSelector: "app=argo", Limit: 2},
+		{LabelSelector: "app=argo", Limit: 2, Continue: "next"},
+	}, requests)
+}
@@ -0,0 +1,123 @@
+package kube
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/argoproj/pkg/v2/kubeclientmetrics"
+)
+
+const (
+	DefaultRequestTimeout = 30 * time.Second
+	DefaultListTimeout    = 2 * time.Minute
+)
+
+// TimeoutOptions configures the timeouts applied by AddDefaultTimeoutTransportWrapper. Zero values
+// are replaced by the defaults above.
+type TimeoutOptions struct {
+	// Default applies to all requests without a more specific timeout
+	Default time.Duration
+	// List applies to list requests, which can take much longer than other requests for large
+	// collections
+	List time.Duration
+	// Verbs overrides the timeout of individual verbs
+	Verbs map[kubeclientmetrics.K8sRequestVerb]time.Duration
+}
+
+func (o TimeoutOptions) timeout(verb kubeclientmetrics.K8sRequestVerb) time.Duration {
+	if d, ok := o.Verbs[verb]; ok {
+		return d
+	}
+	if verb == kubeclientmetrics.List {
+		return o.List
+	}
+	return o.Default
+}
+
+// AddDefaultTimeoutTransportWrapper adds a transport wrapper which applies a timeout to requests
+// whose context has no deadline, so that a hung API server cannot block controller workers
+// forever. Watches, followed logs, upgraded connections and the exec, attach, portforward and
+// proxy subresources are long-running by design and are never given a timeout. Unlike
+// rest.Config.Timeout, requests which already have a deadline are left alone.
+func AddDefaultTimeoutTransportWrapper(config *rest.Config, opts TimeoutOptions) *rest.Config {
+	if opts.Default <= 0 {
+		opts.Default = DefaultRequestTimeout
+	}
+	if opts.List <= 0 {
+		opts.List = DefaultListTimeout
+	}
+	wrap := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return &timeoutRoundTripper{roundTripper: rt, opts: opts}
+	}
+	return config
+}
+
+type timeoutRoundTripper struct {
+	roundTripper http.RoundTripper
+	opts         TimeoutOptions
+}
+
+func (t *timeoutRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if _, ok := r.Context().Deadline(); ok {
+		return t.roundTripper.RoundTrip(r)
+	}
+	verb := kubeclientmetrics.ResolveVerb(r)
+	timeout := t.opts.timeout(verb)
+	if timeout <= 0 || verb == kubeclientmetrics.Watch || isStreaming(r) {
+		return t.roundTripper.RoundTrip(r)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	resp, err := t.roundTripper.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the timeout covers reading the body, so the context must outlive RoundTrip
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// streamingSubresources are the subresources which stream until the client or server closes them
+var streamingSubresources = map[string]bool{"exec": true, "attach": true, "portforward": true, "proxy": true}
+
+// isStreaming returns true for requests other than watches which are long-running
+func isStreaming(r *http.Request) bool {
+	if r.URL.Query().Get("follow") == "true" || r.Header.Get("Upgrade") != "" {
+		return true
+	}
+	for _, v := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), "upgrade") {
+			return true
+		}
+	}
+	// /api/{version}/[namespaces/{namespace}/]{resource}/{name}/{subresource}, or /apis/{group}/...
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	i := 2
+	if len(parts) > 0 && parts[0] == "apis" {
+		i = 3
+	}
+	if len(parts) > i+2 && parts[i] == "namespaces" {
+		i += 2
+	}
+	return len(parts) > i+2 && streamingSubresources[parts[i+2]]
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,129 @@
+package kube
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/argoproj/pkg/v2/kubeclientmetrics"
+)
+
+type deadlineRecorder struct {
+	lock      sync.Mutex
+	deadlines map[string]time.Duration
+}
+
+func (d *deadlineRecorder) RoundTrip(r *http.Request) (*http.Response, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var remaining time.Duration
+	if deadline, ok := r.Context().Deadline(); ok {
+		remaining = time.Until(deadline).Round(time.Second)
+	}
+	d.deadlines[r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery] = remaining
+	resp := httptest.NewRecorder()
+	resp.Code = http.StatusNotFound
+	return resp.Result(), nil
+}
+
+func TestDefaultTimeout(t *testing.T) {
+	recorder := &deadlineRecorder{deadlines: map[string]time.Duration{}}
+	config := &rest.Config{Host: "https://127.0.0.1"}
+	config.WrapTransport = func(http.RoundTripper) http.RoundTripper { return recorder }
+	config = AddDefaultTimeoutTransportWrapper(config, TimeoutOptions{
+		Verbs: map[kubeclientmetrics.K8sRequestVerb]time.Duration{kubeclientmetrics.Delete: time.Minute},
+	})
+	client := kubernetes.NewForConfigOrDie(config)
+	ctx := context.Background()
+
+	_, _ = client.CoreV1().Pods("default").Get(ctx, "my-pod", metav1.GetOptions{})
+	_, _ = client.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+	_ = client.CoreV1().Pods("default").Delete(ctx, "my-pod", metav1.DeleteOptions{})
+	_, _ = client.CoreV1().Pods("default").Watch(ctx, metav1.ListOptions{})
+	withDeadline, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	_, _ = client.CoreV1().Pods("default").Get(withDeadline, "other-pod", metav1.GetOptions{})
+
+	assert.Equal(t, map[string]time.Duration{
+		"GET /api/v1/namespaces/default/pods/my-pod?":    DefaultRequestTimeout,
+		"GET /api/v1/namespaces/default/pods?":           DefaultListTimeout,
+		"DELETE /api/v1/namespaces/default/pods/my-pod?": time.Minute,
+		"GET /api/v1/namespaces/default/pods?watch=true": 0,
+		"GET /api/v1/namespaces/default/pods/other-pod?": 5 * time.Second,
+	}, recorder.deadlines)
+}
+
+func TestDefaultTimeoutStreaming(t *testing.T) {
+	recorder := &deadlineRecorder{deadlines: map[string]time.Duration{}}
+	rt := &timeoutRoundTripper{roundTripper: recorder, opts: TimeoutOptions{Default: time.Minute, List: time.Minute}}
+	for _, tc := range []struct {
+		path   string
+		header http.Header
+	}{
+		{path: "/api/v1/namespaces/default/pods/my-pod/log?follow=true"},
+		{path: "/api/v1/namespaces/default/pods/my-pod/log?container=main"},
+		{path: "/api/v1/namespaces/default/pods/my-pod/exec?command=sh"},
+		{path: "/api/v1/namespaces/default/pods/my-pod/attach?"},
+		{path: "/api/v1/namespaces/default/pods/my-pod/portforward?"},
+		{path: "/api/v1/namespaces/default/services/my-svc/proxy/metrics?"},
+		{path: "/api/v1/nodes/my-node/proxy/stats?"},
+		{path: "/apis/apps/v1/namespaces/default/deployments/my-deploy/status?"},
+		{path: "/api/v1/namespaces/proxy?"},
+		{path: "/api/v1/namespaces/default/pods/upgrade?", header: http.Header{"Connection": {"keep-alive, Upgrade"}}},
+		{path: "/api/v1/namespaces/default/pods/spdy?", header: http.Header{"Upgrade": {"SPDY/3.1"}}},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "https://127.0.0.1"+tc.path, nil)
+		for k, v := range tc.header {
+			r.Header[k] = v
+		}
+		resp, err := rt.RoundTrip(r)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	assert.Equal(t, map[string]time.Duration{
+		"GET /api/v1/namespaces/default/pods/my-pod/log?follow=true":         0,
+		"GET /api/v1/namespaces/default/pods/my-pod/log?container=main":      time.Minute,
+		"GET /api/v1/namespaces/default/pods/my-pod/exec?command=sh":         0,
+		"GET /api/v1/namespaces/default/pods/my-pod/attach?":                 0,
+		"GET /api/v1/namespaces/default/pods/my-pod/portforward?":            0,
+		"GET /api/v1/namespaces/default/services/my-svc/proxy/metrics?":      0,
+		"GET /api/v1/nodes/my-node/proxy/stats?":                             0,
+		"GET /apis/apps/v1/namespaces/default/deployments/my-deploy/status?": time.Minute,
+		"GET /api/v1/namespaces/proxy?":                                      time.Minute,
+		"GET /api/v1/namespaces/default/pods/upgrade?":                       0,
+		"GET /api/v1/namespaces/default/pods/spdy?":                          0,
+	}, recorder.deadlines)
+}
+
+func TestDefaultTimeoutCoversBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+	config := AddDefaultTimeoutTransportWrapper(&rest.Config{Host: ts.URL}, TimeoutOptions{Default: 100 * time.Millisecond})
+	rt, err := rest.TransportFor(config)
+	require.NoError(t, err)
+	req, _ := http.NewRequest("GET", ts.URL+"/api/v1/namespaces/default/pods/my-pod", nil)
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	start := time.Now()
+	_, err = io.Copy(io.Discard, resp.Body)
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "deadline") || strings.Contains(err.Error(), "canceled"), err.Error())
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,264 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// RestartReason describes why a watch was re-established
+type RestartReason string
+
+const (
+	// RestartExpired means the resource version was too old (410 Gone) and a re-list was performed
+	RestartExpired RestartReason = "Expired"
+	// RestartDisconnected means the server closed the watch and it was resumed from the last
+	// resource version. Watches closed before delivering any event are resumed after a backoff.
+	RestartDisconnected RestartReason = "Disconnected"
+	// RestartError means establishing the watch failed and was retried after a backoff
+	RestartError RestartReason = "Error"
+)
+
+// WatchOptions configures Watch
+type WatchOptions struct {
+	// ListOptions holds the label and field selectors used for listing and watching
+	ListOptions metav1.ListOptions
+	// MinBackoff and MaxBackoff bound the exponential backoff between failed attempts
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// OnRestart is called every time the watch is re-established, e.g. to increment a metric
+	OnRestart func(reason RestartReason)
+}
+
+// WatchHandler receives the events of a watch. Returning an error stops the watch.
+type WatchHandler func(event watch.Event) error
+
+// Watch lists the objects and then watches them until the context is cancelled or the handler
+// returns an error. Disconnected watches are resumed from the last seen resource version. When the
+// resource version has expired, the objects are listed again and the difference with the last known
+// state is delivered as Added, Modified and Deleted events, so that the handler sees a consistent
+// stream. Bookmark and error events are handled internally and never passed to the handler.
+func Watch(ctx context.Context, lw cache.ListerWatcher, opts WatchOptions, handler WatchHandler) error {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	w := &watcher{
+		lw:      lw,
+		opts:    opts,
+		handler: handler,
+		known:   map[string]runtime.Object{},
+		backoff: opts.MinBackoff,
+	}
+	return w.run(ctx)
+}
+
+type watcher struct {
+	lw              cache.ListerWatcher
+	opts            WatchOptions
+	handler         WatchHandler
+	known           map[string]runtime.Object
+	resourceVersion string
+	backoff         time.Duration
+}
+
+func (w *watcher) run(ctx context.Context) error {
+	needList := true
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if needList {
+			if err := w.list(); err != nil {
+				var herr handlerError
+				if errors.As(err, &herr) {
+					return herr.err
+				}
+				logging.FromContext(ctx).Warnf("list failed: %v", err)
+				if err := w.sleep(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			needList = false
+		}
+
+		reason, received, err := w.watch(ctx)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if w.opts.OnRestart != nil {
+			w.opts.OnRestart(reason)
+		}
+		switch reason {
+		case RestartExpired:
+			needList = true
+		case RestartDisconnected:
+			// a server which keeps closing watches right away must not be hammered
+			if !received {
+				if err := w.sleep(ctx); err != nil {
+					return err
+				}
+			}
+		case RestartError:
+			if err := w.sleep(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handlerError distinguishes errors returned by the handler from list failures
+type handlerError struct {
+	err error
+}
+
+func (e handlerError) Error() string {
+	return e.err.Error()
+}
+
+// list fetches the current objects and delivers the difference with the known state
+func (w *watcher) list() error {
+	options := w.opts.ListOptions
+	options.ResourceVersion = ""
+	obj, err := w.lw.List(options)
+	if err != nil {
+		return err
+	}
+	listMeta, err := meta.ListAccessor(obj)
+	if err != nil {
+		return err
+	}
+	items, err := meta.ExtractList(obj)
+	if err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		key, err := cache.MetaNamespaceKeyFunc(item)
+		if err != nil {
+			return err
+		}
+		seen[key] = true
+		eventType := watch.Added
+		if previous, ok := w.known[key]; ok {
+			if resourceVersion(previous) == resourceVersion(item) {
+				continue
+			}
+			eventType = watch.Modified
+		}
+		w.known[key] = item
+		if err := w.handler(watch.Event{Type: eventType, Object: item}); err != nil {
+			return handlerError{err}
+		}
+	}
+	for key, previous := range w.known {
+		if !seen[key] {
+			delete(w.known, key)
+			if err := w.handler(watch.Event{Type: watch.Deleted, Object: previous}); err != nil {
+				return handlerError{err}
+			}
+		}
+	}
+	w.resourceVersion = listMeta.GetResourceVersion()
+	w.backoff = w.opts.MinBackoff
+	return nil
+}
+
+// watch consumes one watch until it ends, and returns why it ended and whether any event was
+// received. Only handler errors and context cancellation are returned as errors.
+func (w *watcher) watch(ctx context.Context) (RestartReason, bool, error) {
+	options := w.opts.ListOptions
+	options.ResourceVersion = w.resourceVersion
+	options.AllowWatchBookmarks = true
+	wi, err := w.lw.Watch(options)
+	if err != nil {
+		if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+			return RestartExpired, false, nil
+		}
+		logging.FromContext(ctx).Warnf("watch failed: %v", err)
+		return RestartError, false, nil
+	}
+	defer wi.Stop()
+
+	received := false
+	for {
+		select {
+		case <-ctx.Done():
+			return "", received, ctx.Err()
+		case event, ok := <-wi.ResultChan():
+			if !ok {
+				return RestartDisconnected, received, nil
+			}
+			received = true
+			w.backoff = w.opts.MinBackoff
+			switch event.Type {
+			case watch.Error:
+				err := apierrors.FromObject(event.Object)
+				if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+					return RestartExpired, received, nil
+				}
+				logging.FromContext(ctx).Warnf("watch error: %v", err)
+				return RestartError, received, nil
+			case watch.Bookmark:
+				w.resourceVersion = resourceVersion(event.Object)
+				continue
+			case watch.Added, watch.Modified:
+				key, err := cache.MetaNamespaceKeyFunc(event.Object)
+				if err != nil {
+					return "", received, fmt.Errorf("unexpected watch object: %w", err)
+				}
+				w.known[key] = event.Object
+			case watch.Deleted:
+				key, err := cache.MetaNamespaceKeyFunc(event.Object)
+				if err != nil {
+					return "", received, fmt.Errorf("unexpected watch object: %w", err)
+				}
+				delete(w.known, key)
+			}
+			w.resourceVersion = resourceVersion(event.Object)
+			if err := w.handler(event); err != nil {
+				return "", received, err
+			}
+		}
+	}
+}
+
+func (w *watcher) sleep(ctx context.Context) error {
+	timer := time.NewTimer(w.backoff)
+	defer timer.Stop()
+	w.backoff *= 2
+	if w.backoff > w.opts.MaxBackoff {
+		w.backoff = w.opts.MaxBackoff
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func resourceVersion(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	return accessor.GetResourceVersion()
+}
@@ -0,0 +1,176 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func pod(name, resourceVersion string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "argo", Name: name, ResourceVersion: resourceVersion}}
+}
+
+type fakeListerWatcher struct {
+	lock     sync.Mutex
+	lists    []*corev1.PodList
+	watches  []*watch.FakeWatcher
+	watchRVs []string
+}
+
+func (f *fakeListerWatcher) List(options metav1.ListOptions) (runtime.Object, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	list := f.lists[0]
+	f.lists = f.lists[1:]
+	return list, nil
+}
+
+func (f *fakeListerWatcher) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.watchRVs = append(f.watchRVs, options.ResourceVersion)
+	if len(f.watches) == 0 {
+		return nil, errors.New("no more watches")
+	}
+	w := f.watches[0]
+	f.watches = f.watches[1:]
+	return w, nil
+}
+
+type recorder struct {
+	events chan string
+}
+
+func (r *recorder) handle(event watch.Event) error {
+	r.events <- string(event.Type) + " " + event.Object.(*corev1.Pod).Name
+	return nil
+}
+
+func (r *recorder) next(t *testing.T) string {
+	select {
+	case e := <-r.events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return ""
+	}
+}
+
+func TestWatch(t *testing.T) {
+	first, second, third := watch.NewFake(), watch.NewFake(), watch.NewFake()
+	lw := &fakeListerWatcher{
+		lists: []*corev1.PodList{
+			{ListMeta: metav1.ListMeta{ResourceVersion: "10"}, Items: []corev1.Pod{*pod("a", "1"), *pod("b", "2")}},
+			{ListMeta: metav1.ListMeta{ResourceVersion: "30"}, Items: []corev1.Pod{*pod("a", "1"), *pod("c", "25"), *pod("d", "26")}},
+		},
+		watches: []*watch.FakeWatcher{first, second, third},
+	}
+	var lock sync.Mutex
+	var restarts []RestartReason
+	opts := WatchOptions{OnRestart: func(reason RestartReason) {
+		lock.Lock()
+		defer lock.Unlock()
+		restarts = append(restarts, reason)
+	}}
+	rec := &recorder{events: make(chan string, 10)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Watch(ctx, lw, opts, rec.handle)
+	}()
+
+	assert.Equal(t, "ADDED a", rec.next(t))
+	assert.Equal(t, "ADDED b", rec.next(t))
+
+	first.Modify(pod("b", "11"))
+	assert.Equal(t, "MODIFIED b", rec.next(t))
+	first.Action(watch.Bookmark, pod("", "15"))
+	first.Stop()
+
+	// resumed from the bookmark, then the resource version expires
+	second.Add(pod("c", "20"))
+	assert.Equal(t, "ADDED c", rec.next(t))
+	second.Error(&apierrors.NewResourceExpired("too old").ErrStatus)
+
+	// the re-list delivers the difference: c changed, d is new, b was deleted
+	assert.Equal(t, "MODIFIED c", rec.next(t))
+	assert.Equal(t, "ADDED d", rec.next(t))
+	assert.Equal(t, "DELETED b", rec.next(t))
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	assert.Equal(t, []string{"10", "15", "30"}, lw.watchRVs)
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []RestartReason{RestartDisconnected, RestartExpired}, restarts)
+}
+
+func TestWatchHandlerError(t *testing.T) {
+	w := watch.NewFake()
+	lw := &fakeListerWatcher{
+		lists:   []*corev1.PodList{{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}},
+		watches: []*watch.FakeWatcher{w},
+	}
+	stop := errors.New("stop")
+	done := make(chan error)
+	go func() {
+		done <- Watch(context.Background(), lw, WatchOptions{}, func(event watch.Event) error { return stop })
+	}()
+	w.Add(pod("a", "2"))
+	assert.ErrorIs(t, <-done, stop)
+}
+
+func TestWatchRetriesErrors(t *testing.T) {
+	lw := &fakeListerWatcher{lists: []*corev1.PodList{{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	restarts := 0
+	err := Watch(ctx, lw, WatchOptions{MinBackoff: 10 * time.Millisecond, OnRestart: func(reason RestartReason) {
+		assert.Equal(t, RestartError, reason)
+		restarts++
+	}}, func(event watch.Event) error { return nil })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Greater(t, restarts, 1)
+}
+
+func TestWatchBacksOffEmptyDisconnects(t *testing.T) {
+	closed := func() *watch.FakeWatcher {
+		w := watch.NewFake()
+		w.Stop()
+		return w
+	}
+	withEvent := watch.NewFakeWithChanSize(1, false)
+	withEvent.Add(pod("a", "2"))
+	withEvent.Stop()
+	lw := &fakeListerWatcher{
+		lists:   []*corev1.PodList{{ListMeta: metav1.ListMeta{ResourceVersion: "1"}}},
+		watches: []*watch.FakeWatcher{withEvent, closed(), closed()},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var restarts []time.Time
+	err := Watch(ctx, lw, WatchOptions{MinBackoff: 50 * time.Millisecond, OnRestart: func(reason RestartReason) {
+		restarts = append(restarts, time.Now())
+		if len(restarts) == 3 {
+			cancel()
+		}
+	}}, func(event watch.Event) error { return nil })
+	require.ErrorIs(t, err, context.Canceled)
+	require.Len(t, restarts, 3)
+	// the watch which delivered an event is resumed right away, empty ones after a growing backoff
+	assert.Less(t, restarts[1].Sub(restarts[0]), 40*time.Millisecond)
+	assert.GreaterOrEqual(t, restarts[2].Sub(restarts[1]), 50*time.Millisecond)
+	assert.Equal(t, []string{"1", "2", "2"}, lw.watchRVs)
+}
@@ -0,0 +1,81 @@
+package kubeclientmetrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// ClientOptions describes a logical client sharing a base rest.Config with other clients
+type ClientOptions struct {
+	// Name is reported as ResourceInfo.ClientName and appended to the user agent
+	Name string
+	// QPS, Burst and Timeout override the values of the base config if non-zero
+	QPS     float32
+	Burst   int
+	Timeout time.Duration
+}
+
+// NewClientConfig returns a copy of config for a logical client, e.g. a low-priority client for
+// bulk lists next to a client for status updates. Requests made through the copy are reported with
+// its name by the metrics transport and rate limiter wrappers of the base config, so
+// AddMetricsTransportWrapper and AddRateLimiterWrapper must be called on the base config before it
+// is copied.
+func NewClientConfig(config *rest.Config, opts ClientOptions) *rest.Config {
+	c := rest.CopyConfig(config)
+	if opts.QPS != 0 {
+		c.QPS = opts.QPS
+	}
+	if opts.Burst != 0 {
+		c.Burst = opts.Burst
+	}
+	if opts.Timeout != 0 {
+		c.Timeout = opts.Timeout
+	}
+	if limiter, ok := c.RateLimiter.(*observedRateLimiter); ok {
+		c.RateLimiter = limiter.forClient(c, opts)
+	}
+	if opts.Name == "" {
+		return c
+	}
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = rest.DefaultKubernetesUserAgent()
+	}
+	c.UserAgent = userAgent + " " + opts.Name
+	wrap := c.WrapTransport
+	c.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return &clientNameRoundTripper{roundTripper: rt, name: opts.Name}
+	}
+	return c
+}
+
+type clientNameKey struct{}
+
+// WithClientName returns a copy of ctx which makes the metrics transport wrapper report requests
+// with the given client name
+func WithClientName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, clientNameKey{}, name)
+}
+
+func clientNameFrom(ctx context.Context) string {
+	name, _ := ctx.Value(clientNameKey{}).(string)
+	return name
+}
+
+type clientNameRoundTripper struct {
+	roundTripper http.RoundTripper
+	name         string
+}
+
+func (c *clientNameRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if clientNameFrom(r.Context()) == "" {
+		r = r.WithContext(WithClientName(r.Context(), c.name))
+	}
+	return c.roundTripper.RoundTrip(r)
+}
@@ -0,0 +1,93 @@
+package kubeclientmetrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Relative costs of requests to the API server. They are rough estimates meant to rank clients by
+// the load they cause, not to model the API server precisely.
+const (
+	CostGet   = 1.0
+	CostWatch = 1.0
+	CostWrite = 2.0
+	CostList  = 5.0
+	// CostListClusterScopedFactor is applied to lists across all namespaces
+	CostListClusterScopedFactor = 4.0
+	// CostListUnpaginatedFactor is applied to lists which return every object at once, i.e. without
+	// a limit, with a limit above LargeListLimit, or with resourceVersion=0 which ignores the limit
+	CostListUnpaginatedFactor = 4.0
+	LargeListLimit            = 500
+)
+
+// EstimateCost returns the approximate cost of the request described by r and info
+func EstimateCost(r *http.Request, info ResourceInfo) float64 {
+	switch info.Verb {
+	case Get:
+		return CostGet
+	case Watch:
+		return CostWatch
+	case Create, Update, Patch, Delete:
+		return CostWrite
+	case List:
+		cost := CostList
+		if info.Namespace == "" {
+			cost *= CostListClusterScopedFactor
+		}
+		query := r.URL.Query()
+		limit, _ := strconv.Atoi(query.Get("limit"))
+		if limit <= 0 || limit > LargeListLimit || query.Get("resourceVersion") == "0" {
+			cost *= CostListUnpaginatedFactor
+		}
+		return cost
+	}
+	return CostGet
+}
+
+// KindCost is the cumulative cost of the requests for a kind
+type KindCost struct {
+	Requests int64
+	Cost     float64
+}
+
+// CostTracker accumulates the estimated cost of requests per kind. Its Inc method can be passed to
+// AddMetricsTransportWrapper.
+type CostTracker struct {
+	lock  sync.Mutex
+	costs map[string]KindCost
+}
+
+// NewCostTracker returns an empty CostTracker
+func NewCostTracker() *CostTracker {
+	return &CostTracker{costs: map[string]KindCost{}}
+}
+
+// Inc adds the cost of the request to the total of its kind
+func (t *CostTracker) Inc(info ResourceInfo) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	c := t.costs[info.Kind]
+	c.Requests++
+	c.Cost += info.Cost
+	t.costs[info.Kind] = c
+	return nil
+}
+
+// Reset forgets all costs
+func (t *CostTracker) Reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.costs = map[string]KindCost{}
+}
+
+// Costs returns a copy of the cumulative costs per kind
+func (t *CostTracker) Costs() map[string]KindCost {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	costs := make(map[string]KindCost, len(t.costs))
+	for kind, c := range t.costs {
+		costs[kind] = c
+	}
+	return costs
+}
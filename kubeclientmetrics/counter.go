@@ -0,0 +1,85 @@
+package kubeclientmetrics
+
+import "sync"
+
+// RequestKey identifies the requests counted together by a Counter
+type RequestKey struct {
+	Kind        string
+	Subresource string
+	Verb        K8sRequestVerb
+}
+
+// Snapshot is a copy of the counts of a Counter
+type Snapshot struct {
+	Total       int64
+	Requests    map[RequestKey]int64
+	StatusCodes map[int]int64
+	// Retryable is the number of responses which client-go retries, see ResourceInfo.Retryable
+	Retryable int64
+}
+
+// Count returns the number of requests of the kind and verb, including requests to subresources of
+// the kind. An empty kind or verb matches all kinds or verbs.
+func (s Snapshot) Count(kind string, verb K8sRequestVerb) int64 {
+	var count int64
+	for key, n := range s.Requests {
+		if (kind == "" || key.Kind == kind) && (verb == "" || key.Verb == verb) {
+			count += n
+		}
+	}
+	return count
+}
+
+// Counter counts requests by kind, verb and status code, so that tests can assert on the requests
+// made by a client without scraping metrics. Its Inc method can be passed to
+// AddMetricsTransportWrapper.
+type Counter struct {
+	lock     sync.Mutex
+	snapshot Snapshot
+}
+
+// NewCounter returns a Counter with all counts at zero
+func NewCounter() *Counter {
+	c := &Counter{}
+	c.Reset()
+	return c
+}
+
+// Inc counts the request
+func (c *Counter) Inc(info ResourceInfo) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.snapshot.Total++
+	c.snapshot.Requests[RequestKey{Kind: info.Kind, Subresource: info.Subresource, Verb: info.Verb}]++
+	c.snapshot.StatusCodes[info.StatusCode]++
+	if info.Retryable {
+		c.snapshot.Retryable++
+	}
+	return nil
+}
+
+// Snapshot returns a copy of the current counts
+func (c *Counter) Snapshot() Snapshot {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s := Snapshot{
+		Total:       c.snapshot.Total,
+		Retryable:   c.snapshot.Retryable,
+		Requests:    make(map[RequestKey]int64, len(c.snapshot.Requests)),
+		StatusCodes: make(map[int]int64, len(c.snapshot.StatusCodes)),
+	}
+	for k, v := range c.snapshot.Requests {
+		s.Requests[k] = v
+	}
+	for k, v := range c.snapshot.StatusCodes {
+		s.StatusCodes[k] = v
+	}
+	return s
+}
+
+// Reset sets all counts to zero
+func (c *Counter) Reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.snapshot = Snapshot{Requests: map[RequestKey]int64{}, StatusCodes: map[int]int64{}}
+}
@@ -0,0 +1,85 @@
+package kubeclientmetrics
+
+import (
+	"sort"
+	"time"
+)
+
+// Exemplar links an observation of a histogram to the trace of the request, so that e.g. a slow
+// request can be looked up from the latency metrics. See ResourceInfo.TraceID.
+type Exemplar struct {
+	TraceID string
+	SpanID  string
+	Value   time.Duration
+	Time    time.Time
+}
+
+// HistogramSnapshot is a copy of the observations of a histogram
+type HistogramSnapshot struct {
+	// Buckets are the upper bounds of the buckets
+	Buckets []time.Duration
+	// Counts are the cumulative number of requests which took at most the bucket duration, as in
+	// Prometheus histograms. Requests slower than the last bucket are only included in Count.
+	Counts []int64
+	Count  int64
+	Sum    time.Duration
+	// Exemplars holds the latest traced observation of each bucket, and of the requests slower
+	// than the last bucket at the end. Entries are nil for buckets without traced observations.
+	Exemplars []*Exemplar
+}
+
+// histogram counts durations in buckets and keeps an exemplar per bucket. It is not safe for
+// concurrent use.
+type histogram struct {
+	buckets   []time.Duration
+	counts    []int64
+	count     int64
+	sum       time.Duration
+	exemplars []*Exemplar
+}
+
+// sortedBuckets returns a sorted copy of the bucket upper bounds
+func sortedBuckets(buckets []time.Duration) []time.Duration {
+	buckets = append([]time.Duration(nil), buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	return buckets
+}
+
+func newHistogram(buckets []time.Duration) *histogram {
+	return &histogram{
+		buckets:   buckets,
+		counts:    make([]int64, len(buckets)),
+		exemplars: make([]*Exemplar, len(buckets)+1),
+	}
+}
+
+// observe records the duration. The trace and span IDs are recorded as exemplar of the bucket of
+// the duration if they are set.
+func (h *histogram) observe(d time.Duration, traceID, spanID string, now time.Time) {
+	bucket := sort.Search(len(h.buckets), func(i int) bool { return d <= h.buckets[i] })
+	for i := bucket; i < len(h.counts); i++ {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += d
+	if traceID != "" {
+		h.exemplars[bucket] = &Exemplar{TraceID: traceID, SpanID: spanID, Value: d, Time: now}
+	}
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{
+		Buckets:   h.buckets,
+		Counts:    append([]int64(nil), h.counts...),
+		Count:     h.count,
+		Sum:       h.sum,
+		Exemplars: make([]*Exemplar, len(h.exemplars)),
+	}
+	for i, e := range h.exemplars {
+		if e != nil {
+			c := *e
+			s.Exemplars[i] = &c
+		}
+	}
+	return s
+}
@@ -0,0 +1,137 @@
+package kubeclientmetrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the buckets used by a LatencyHistogram by default
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+// LatencyKey identifies the requests observed together by a LatencyHistogram
+type LatencyKey struct {
+	Verb        K8sRequestVerb
+	Kind        string
+	Subresource string
+	Namespace   string
+	StatusCode  int
+}
+
+// latencyDesc describes the histograms exported by LatencyHistogram
+var latencyDesc = prometheus.NewDesc(
+	"k8s_client_request_duration_seconds",
+	"Latency of requests to the Kubernetes API until the response headers were received.",
+	[]string{"verb", "kind", "subresource", "namespace", "status_code"}, nil,
+)
+
+// LatencyHistogram records the latency of requests by verb, kind, subresource, namespace and status
+// code. Its Observe method can be passed to AddMetricsTransportWrapper. It is a Prometheus
+// collector exporting k8s_client_request_duration_seconds, with the trace and span IDs of traced
+// requests as exemplars in the OpenMetrics format, and its snapshot can be exported to other
+// metrics systems. Keying by namespace multiplies the number of series, so clients which access
+// many namespaces should aggregate before exporting.
+type LatencyHistogram struct {
+	buckets []time.Duration
+	now     func() time.Time
+
+	lock       sync.Mutex
+	histograms map[LatencyKey]*histogram
+}
+
+// NewLatencyHistogram returns a LatencyHistogram with the given bucket upper bounds, or
+// DefaultLatencyBuckets if none are given
+func NewLatencyHistogram(buckets ...time.Duration) *LatencyHistogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	return &LatencyHistogram{
+		buckets:    sortedBuckets(buckets),
+		now:        time.Now,
+		histograms: map[LatencyKey]*histogram{},
+	}
+}
+
+// Observe records the duration of the request
+func (h *LatencyHistogram) Observe(info ResourceInfo) error {
+	key := LatencyKey{
+		Verb:        info.Verb,
+		Kind:        info.Kind,
+		Subresource: info.Subresource,
+		Namespace:   info.Namespace,
+		StatusCode:  info.StatusCode,
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	histogram, ok := h.histograms[key]
+	if !ok {
+		histogram = newHistogram(h.buckets)
+		h.histograms[key] = histogram
+	}
+	histogram.observe(info.Duration, info.TraceID, info.SpanID, h.now())
+	return nil
+}
+
+// Snapshot returns a copy of the current observations
+func (h *LatencyHistogram) Snapshot() map[LatencyKey]HistogramSnapshot {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	s := make(map[LatencyKey]HistogramSnapshot, len(h.histograms))
+	for key, histogram := range h.histograms {
+		s[key] = histogram.snapshot()
+	}
+	return s
+}
+
+// Reset discards all observations
+func (h *LatencyHistogram) Reset() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.histograms = map[LatencyKey]*histogram{}
+}
+
+// Describe implements prometheus.Collector
+func (h *LatencyHistogram) Describe(ch chan<- *prometheus.Desc) {
+	ch <- latencyDesc
+}
+
+// Collect implements prometheus.Collector
+func (h *LatencyHistogram) Collect(ch chan<- prometheus.Metric) {
+	for key, s := range h.Snapshot() {
+		buckets := make(map[float64]uint64, len(s.Buckets))
+		for i, upper := range s.Buckets {
+			buckets[upper.Seconds()] = uint64(s.Counts[i])
+		}
+		m := prometheus.MustNewConstHistogram(latencyDesc, uint64(s.Count), s.Sum.Seconds(), buckets,
+			string(key.Verb), key.Kind, key.Subresource, key.Namespace, strconv.Itoa(key.StatusCode))
+		var exemplars []prometheus.Exemplar
+		for _, e := range s.Exemplars {
+			if e != nil {
+				exemplars = append(exemplars, prometheus.Exemplar{
+					Value:     e.Value.Seconds(),
+					Labels:    prometheus.Labels{"trace_id": e.TraceID, "span_id": e.SpanID},
+					Timestamp: e.Time,
+				})
+			}
+		}
+		if len(exemplars) > 0 {
+			m = prometheus.MustNewMetricWithExemplars(m, exemplars...)
+		}
+		ch <- m
+	}
+}
@@ -6,11 +6,15 @@ import (
 	"net/http"
 	"path"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/rest"
+
+	"github.com/argoproj/pkg/v2/logging"
 )
 
 type K8sRequestVerb string
@@ -31,15 +35,36 @@ const findPathRegex = `/v\d\w*?(/[a-zA-Z0-9-]*)(/[a-zA-Z0-9-]*)?(/[a-zA-Z0-9-]*)
 var (
 	processPath       = regexp.MustCompile(findPathRegex)
 	isNamespacedQuery = regexp.MustCompile(`/.*/namespaces/[a-z0-9-]+/[a-z0-9-]+(/[a-z0-9-]+)?`)
+	// namespaceSubresources are the subresources of namespaces, which are not resources inside the
+	// namespace
+	namespaceSubresources = map[string]bool{"status": true, "finalize": true}
 )
 
 type ResourceInfo struct {
-	Server     string
-	Kind       string
-	Namespace  string
-	Name       string
-	Verb       K8sRequestVerb
-	StatusCode int
+	Server    string
+	Kind      string
+	Namespace string
+	Name      string
+	// Subresource is the subresource of the object, e.g. status, scale or exec, or empty for
+	// requests to the object itself
+	Subresource string
+	Verb        K8sRequestVerb
+	StatusCode  int
+	// Duration is the time until the response headers were received. For watches this is the time
+	// to establish the watch, not its lifetime.
+	Duration time.Duration
+	// Retryable is set for 429 and 5xx responses with a Retry-After header, which client-go
+	// retries after RetryAfter. Every attempt is reported separately.
+	Retryable  bool
+	RetryAfter time.Duration
+	// Cost is the estimated cost of the request to the API server, see EstimateCost
+	Cost float64
+	// TraceID and SpanID identify the span of the request context when tracing is enabled. They
+	// can be attached as exemplars to metrics, to link a slow request to its trace.
+	TraceID string
+	SpanID  string
+	// ClientName is the name of the logical client which made the request, see NewClientConfig
+	ClientName string
 }
 
 func (ri ResourceInfo) HasAllFields() bool {
@@ -59,7 +84,8 @@ type metricsRoundTripper struct {
 // of segments while a GET request has an even number of segments. Watch is determined if the query
 // parameter watch=true is present in the request.
 func discernGetRequest(r *http.Request) K8sRequestVerb {
-	segments := processPath.FindStringSubmatch(r.URL.Path)
+	resourcePath, _ := splitSubresource(r.URL.Path)
+	segments := processPath.FindStringSubmatch(resourcePath)
 	unusedGroup := 0
 	for _, str := range segments {
 		if str == "" {
@@ -77,6 +103,11 @@ func discernGetRequest(r *http.Request) K8sRequestVerb {
 	return Get
 }
 
+// ResolveVerb returns the verb of a request to the Kubernetes API
+func ResolveVerb(r *http.Request) K8sRequestVerb {
+	return resolveK8sRequestVerb(r)
+}
+
 func resolveK8sRequestVerb(r *http.Request) K8sRequestVerb {
 	if r.Method == "POST" {
 		return Create
@@ -96,22 +127,44 @@ func resolveK8sRequestVerb(r *http.Request) K8sRequestVerb {
 	return Unknown
 }
 
+// splitSubresource returns the path of the object and the subresource, if the path is one of a
+// subresource such as /api/v1/namespaces/default/pods/my-pod/status. Other paths are returned
+// unchanged with an empty subresource.
+func splitSubresource(urlPath string) (string, string) {
+	parts := strings.Split(strings.Trim(urlPath, "/"), "/")
+	switch {
+	case len(parts) > 2 && parts[0] == "api":
+		parts = parts[2:]
+	case len(parts) > 3 && parts[0] == "apis":
+		parts = parts[3:]
+	default:
+		return urlPath, ""
+	}
+	if len(parts) > 2 && parts[0] == "namespaces" && !namespaceSubresources[parts[2]] {
+		parts = parts[2:]
+	}
+	if len(parts) != 3 {
+		return urlPath, ""
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(urlPath, "/"), "/"+parts[2]), parts[2]
+}
+
 func handleCreate(r *http.Request) ResourceInfo {
 	kind := path.Base(r.URL.Path)
 	bodyIO, err := r.GetBody()
 	if err != nil {
-		log.WithField("Kind", kind).Warnf("Unable to Process Create request: %v", err)
+		logging.FromContext(r.Context()).WithField("Kind", kind).Warnf("Unable to Process Create request: %v", err)
 		return ResourceInfo{}
 	}
 	body, err := io.ReadAll(bodyIO)
 	if err != nil {
-		log.WithField("Kind", kind).Warnf("Unable to Process Create request: %v", err)
+		logging.FromContext(r.Context()).WithField("Kind", kind).Warnf("Unable to Process Create request: %v", err)
 		return ResourceInfo{}
 	}
 	var obj map[string]interface{}
 	err = json.Unmarshal(body, &obj)
 	if err != nil {
-		log.WithField("Kind", kind).Warnf("Unable to Process Create request: %v", err)
+		logging.FromContext(r.Context()).WithField("Kind", kind).Warnf("Unable to Process Create request: %v", err)
 		return ResourceInfo{}
 	}
 	un := unstructured.Unstructured{Object: obj}
@@ -124,24 +177,51 @@ func handleCreate(r *http.Request) ResourceInfo {
 }
 
 func (mrt *metricsRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
 	resp, roundTimeErr := mrt.roundTripper.RoundTrip(r)
-	info := parseRequest(r)
+	duration := time.Since(start)
+	info := requestInfo(r)
+	info.Duration = duration
 	if resp != nil {
 		info.StatusCode = resp.StatusCode
+		info.RetryAfter, info.Retryable = retryAfter(resp)
+	}
+	info.Cost = EstimateCost(r, info)
+	info.ClientName = clientNameFrom(r.Context())
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		info.TraceID = sc.TraceID().String()
+		info.SpanID = sc.SpanID().String()
 	}
 	_ = mrt.inc(info)
 	return resp, roundTimeErr
 }
 
+// retryAfter returns the delay after which client-go retries the request, using the same rules
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && (resp.StatusCode < 500 || resp.StatusCode > 599) {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
 func parseRequest(r *http.Request) ResourceInfo {
 	var info ResourceInfo
 	verb := resolveK8sRequestVerb(r)
-	path := strings.Split(r.URL.Path, "/")
+	resourcePath, subresource := splitSubresource(r.URL.Path)
+	path := strings.Split(resourcePath, "/")
 	len := len(path)
-	switch verb {
-	case List, Watch:
+	switch {
+	case verb == List || verb == Watch:
 		info.Kind = path[len-1]
-		if isNamespacedQuery.MatchString(r.URL.Path) {
+		if isNamespacedQuery.MatchString(resourcePath) {
 			info.Namespace = path[len-2]
 		}
 		// set info.Name if watch is against a single resource
@@ -156,18 +236,20 @@ func parseRequest(r *http.Request) ResourceInfo {
 			}
 		}
 
-	case Create:
+	// subresources such as exec or eviction are created without a body describing the object
+	case verb == Create && subresource == "":
 		info = handleCreate(r)
-	case Get, Delete, Patch, Update:
+	case verb == Create || verb == Get || verb == Delete || verb == Patch || verb == Update:
 		info.Name = path[len-1]
 		info.Kind = path[len-2]
-		if isNamespacedQuery.MatchString(r.URL.Path) {
+		if isNamespacedQuery.MatchString(resourcePath) {
 			info.Namespace = path[len-3]
 		}
 	default:
-		log.WithField("path", r.URL.Path).WithField("method", r.Method).Warnf("Unknown Request")
+		logging.FromContext(r.Context()).WithField("path", r.URL.Path).WithField("method", r.Method).Warnf("Unknown Request")
 	}
 	info.Server = r.URL.Scheme + "://" + r.URL.Host
+	info.Subresource = subresource
 	info.Verb = verb
 	return info
 }
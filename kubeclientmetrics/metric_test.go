@@ -2,12 +2,24 @@ package kubeclientmetrics
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -269,6 +281,52 @@ func TestParseRequest(t *testing.T) {
 				Name:      "default",
 			},
 		},
+		{
+			testName: "Pod status GET",
+			url:      "https://127.0.0.1/api/v1/namespaces/default/pods/my-pod/status",
+			expected: ResourceInfo{
+				Server:      "https://127.0.0.1",
+				Verb:        Get,
+				Kind:        "pods",
+				Subresource: "status",
+				Namespace:   "default",
+				Name:        "my-pod",
+			},
+		},
+		{
+			testName: "Deployment scale GET",
+			url:      "https://127.0.0.1/apis/apps/v1/namespaces/default/deployments/my-deployment/scale",
+			expected: ResourceInfo{
+				Server:      "https://127.0.0.1",
+				Verb:        Get,
+				Kind:        "deployments",
+				Subresource: "scale",
+				Namespace:   "default",
+				Name:        "my-deployment",
+			},
+		},
+		{
+			testName: "Cluster resource status GET",
+			url:      "https://127.0.0.1/apis/apiextensions.k8s.io/v1/customresourcedefinitions/dummies.argoproj.io/status",
+			expected: ResourceInfo{
+				Server:      "https://127.0.0.1",
+				Verb:        Get,
+				Kind:        "customresourcedefinitions",
+				Subresource: "status",
+				Name:        "dummies.argoproj.io",
+			},
+		},
+		{
+			testName: "Namespace status GET",
+			url:      "https://127.0.0.1/api/v1/namespaces/default/status",
+			expected: ResourceInfo{
+				Server:      "https://127.0.0.1",
+				Verb:        Get,
+				Kind:        "namespaces",
+				Subresource: "status",
+				Name:        "default",
+			},
+		},
 		// Not yet supported
 		// {
 		// 	testName: "Non resource request",
@@ -289,6 +347,19 @@ func TestParseRequest(t *testing.T) {
 	}
 }
 
+func TestParseSubresourceCreate(t *testing.T) {
+	r := newGetRequest("https://127.0.0.1/api/v1/namespaces/default/pods/my-pod/exec?command=ls")
+	r.Method = "POST"
+	assert.Equal(t, ResourceInfo{
+		Server:      "https://127.0.0.1",
+		Verb:        Create,
+		Kind:        "pods",
+		Subresource: "exec",
+		Namespace:   "default",
+		Name:        "my-pod",
+	}, parseRequest(r))
+}
+
 func TestGetRequest(t *testing.T) {
 	expectedStatusCode := 201
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -450,3 +521,362 @@ func TestUnknownRequest(t *testing.T) {
 	client.Discovery().RESTClient().Verb("invalid-verb").Do(context.Background())
 	assert.True(t, executed)
 }
+
+func TestEstimateCost(t *testing.T) {
+	testData := []struct {
+		url      string
+		method   string
+		expected float64
+	}{
+		{"https://127.0.0.1/api/v1/namespaces/default/pods/my-pod", "GET", CostGet},
+		{"https://127.0.0.1/api/v1/namespaces/default/pods/my-pod", "DELETE", CostWrite},
+		{"https://127.0.0.1/api/v1/pods?watch=true&resourceVersion=1", "GET", CostWatch},
+		{"https://127.0.0.1/api/v1/namespaces/default/pods?limit=100", "GET", CostList},
+		{"https://127.0.0.1/api/v1/namespaces/default/pods?limit=100&resourceVersion=0", "GET", CostList * CostListUnpaginatedFactor},
+		{"https://127.0.0.1/api/v1/namespaces/default/pods?limit=1000", "GET", CostList * CostListUnpaginatedFactor},
+		{"https://127.0.0.1/api/v1/pods?limit=100", "GET", CostList * CostListClusterScopedFactor},
+		{"https://127.0.0.1/api/v1/pods", "GET", CostList * CostListClusterScopedFactor * CostListUnpaginatedFactor},
+	}
+	for _, td := range testData {
+		t.Run(td.method+" "+td.url, func(t *testing.T) {
+			r := newGetRequest(td.url)
+			r.Method = td.method
+			assert.Equal(t, td.expected, EstimateCost(r, parseRequest(r)))
+		})
+	}
+}
+
+func TestCostTracker(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	tracker := NewCostTracker()
+	client := kubernetes.NewForConfigOrDie(AddMetricsTransportWrapper(NewConfig(ts.URL), tracker.Inc))
+	_, _ = client.AppsV1().ReplicaSets(metav1.NamespaceDefault).Get(context.Background(), "test", metav1.GetOptions{})
+	_, _ = client.AppsV1().ReplicaSets("").List(context.Background(), metav1.ListOptions{})
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{Limit: 50})
+	assert.Equal(t, map[string]KindCost{
+		"replicasets": {Requests: 2, Cost: CostGet + CostList*CostListClusterScopedFactor*CostListUnpaginatedFactor},
+		"pods":        {Requests: 1, Cost: CostList},
+	}, tracker.Costs())
+	tracker.Reset()
+	assert.Empty(t, tracker.Costs())
+}
+
+func TestCounter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	counter := NewCounter()
+	client := kubernetes.NewForConfigOrDie(AddMetricsTransportWrapper(NewConfig(ts.URL), counter.Inc))
+	_, _ = client.AppsV1().ReplicaSets(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{})
+	_, _ = client.AppsV1().ReplicaSets("").List(context.Background(), metav1.ListOptions{})
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).Get(context.Background(), "test", metav1.GetOptions{})
+	_ = client.CoreV1().Pods(metav1.NamespaceDefault).Delete(context.Background(), "test", metav1.DeleteOptions{})
+
+	s := counter.Snapshot()
+	assert.Equal(t, int64(4), s.Total)
+	assert.Equal(t, int64(2), s.Count("replicasets", List))
+	assert.Equal(t, int64(0), s.Count("replicasets", Get))
+	assert.Equal(t, int64(2), s.Count("pods", ""))
+	assert.Equal(t, int64(2), s.Count("", List))
+	assert.Equal(t, map[int]int64{http.StatusOK: 3, http.StatusNotFound: 1}, s.StatusCodes)
+
+	counter.Reset()
+	assert.Equal(t, int64(0), counter.Snapshot().Total)
+	// the snapshot is not affected by later requests or resets
+	assert.Equal(t, int64(4), s.Total)
+	assert.Equal(t, int64(2), s.Count("", List))
+}
+
+func TestTraceID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	var infos []ResourceInfo
+	client := kubernetes.NewForConfigOrDie(AddMetricsTransportWrapper(NewConfig(ts.URL), func(info ResourceInfo) error {
+		infos = append(infos, info)
+		return nil
+	}))
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).Get(ctx, "traced", metav1.GetOptions{})
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).Get(context.Background(), "untraced", metav1.GetOptions{})
+	require.Len(t, infos, 2)
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", infos[0].TraceID)
+	assert.Equal(t, "0102030405060708", infos[0].SpanID)
+	assert.Empty(t, infos[1].TraceID)
+	assert.Empty(t, infos[1].SpanID)
+}
+
+func TestNewClientConfig(t *testing.T) {
+	var userAgents []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	var names []string
+	base := AddMetricsTransportWrapper(NewConfig(ts.URL), func(info ResourceInfo) error {
+		names = append(names, info.ClientName)
+		return nil
+	})
+	base.QPS = 5
+	base.Burst = 10
+	bulk := NewClientConfig(base, ClientOptions{Name: "bulk-list", QPS: 1, Timeout: time.Minute})
+	assert.Equal(t, float32(1), bulk.QPS)
+	assert.Equal(t, 10, bulk.Burst)
+	assert.Equal(t, time.Minute, bulk.Timeout)
+	assert.Equal(t, float32(5), base.QPS)
+
+	ctx := context.Background()
+	_, _ = kubernetes.NewForConfigOrDie(bulk).CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	_, _ = kubernetes.NewForConfigOrDie(base).CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	_, _ = kubernetes.NewForConfigOrDie(base).CoreV1().Pods("").List(WithClientName(ctx, "adhoc"), metav1.ListOptions{})
+	assert.Equal(t, []string{"bulk-list", "", "adhoc"}, names)
+	require.Len(t, userAgents, 3)
+	assert.True(t, strings.HasSuffix(userAgents[0], " bulk-list"), userAgents[0])
+	assert.Equal(t, rest.DefaultKubernetesUserAgent(), userAgents[1])
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/slow") {
+			time.Sleep(30 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	histogram := NewLatencyHistogram(time.Second, 20*time.Millisecond)
+	now := time.Unix(1000, 0)
+	histogram.now = func() time.Time { return now }
+	client := kubernetes.NewForConfigOrDie(AddMetricsTransportWrapper(NewConfig(ts.URL), histogram.Observe))
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:     trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+		TraceFlags: trace.FlagsSampled,
+	})
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).Get(context.Background(), "fast", metav1.GetOptions{})
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).Get(trace.ContextWithSpanContext(context.Background(), sc), "slow", metav1.GetOptions{})
+	_, _ = client.AppsV1().Deployments(metav1.NamespaceDefault).GetScale(context.Background(), "test", metav1.GetOptions{})
+
+	s := histogram.Snapshot()
+	require.Len(t, s, 2)
+	pods := s[LatencyKey{Verb: Get, Kind: "pods", Namespace: metav1.NamespaceDefault, StatusCode: http.StatusOK}]
+	assert.Equal(t, []time.Duration{20 * time.Millisecond, time.Second}, pods.Buckets)
+	assert.Equal(t, []int64{1, 2}, pods.Counts)
+	assert.Equal(t, int64(2), pods.Count)
+	assert.GreaterOrEqual(t, pods.Sum, 30*time.Millisecond)
+	// the traced slow request is the exemplar of its bucket
+	require.Len(t, pods.Exemplars, 3)
+	assert.Nil(t, pods.Exemplars[0])
+	require.NotNil(t, pods.Exemplars[1])
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", pods.Exemplars[1].TraceID)
+	assert.Equal(t, "0102030405060708", pods.Exemplars[1].SpanID)
+	assert.GreaterOrEqual(t, pods.Exemplars[1].Value, 30*time.Millisecond)
+	assert.Equal(t, now, pods.Exemplars[1].Time)
+	assert.Nil(t, pods.Exemplars[2])
+	scale := s[LatencyKey{Verb: Get, Kind: "deployments", Subresource: "scale", Namespace: metav1.NamespaceDefault, StatusCode: http.StatusOK}]
+	assert.Equal(t, int64(1), scale.Count)
+
+	histogram.Reset()
+	assert.Empty(t, histogram.Snapshot())
+	assert.Equal(t, int64(2), pods.Count)
+}
+
+func TestLatencyHistogramCollector(t *testing.T) {
+	histogram := NewLatencyHistogram(100*time.Millisecond, time.Second)
+	for _, d := range []time.Duration{50 * time.Millisecond, 500 * time.Millisecond, 2 * time.Second} {
+		require.NoError(t, histogram.Observe(ResourceInfo{Verb: Get, Kind: "pods", Namespace: "argo", StatusCode: http.StatusOK, Duration: d}))
+	}
+	require.NoError(t, histogram.Observe(ResourceInfo{Verb: Update, Kind: "pods", Subresource: "status", Namespace: "argo", StatusCode: http.StatusConflict, Duration: 10 * time.Millisecond}))
+
+	expected := `
+# HELP k8s_client_request_duration_seconds Latency of requests to the Kubernetes API until the response headers were received.
+# TYPE k8s_client_request_duration_seconds histogram
+k8s_client_request_duration_seconds_bucket{kind="pods",namespace="argo",status_code="200",subresource="",verb="Get",le="0.1"} 1
+k8s_client_request_duration_seconds_bucket{kind="pods",namespace="argo",status_code="200",subresource="",verb="Get",le="1"} 2
+k8s_client_request_duration_seconds_bucket{kind="pods",namespace="argo",status_code="200",subresource="",verb="Get",le="+Inf"} 3
+k8s_client_request_duration_seconds_sum{kind="pods",namespace="argo",status_code="200",subresource="",verb="Get"} 2.55
+k8s_client_request_duration_seconds_count{kind="pods",namespace="argo",status_code="200",subresource="",verb="Get"} 3
+k8s_client_request_duration_seconds_bucket{kind="pods",namespace="argo",status_code="409",subresource="status",verb="Update",le="0.1"} 1
+k8s_client_request_duration_seconds_bucket{kind="pods",namespace="argo",status_code="409",subresource="status",verb="Update",le="1"} 1
+k8s_client_request_duration_seconds_bucket{kind="pods",namespace="argo",status_code="409",subresource="status",verb="Update",le="+Inf"} 1
+k8s_client_request_duration_seconds_sum{kind="pods",namespace="argo",status_code="409",subresource="status",verb="Update"} 0.01
+k8s_client_request_duration_seconds_count{kind="pods",namespace="argo",status_code="409",subresource="status",verb="Update"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(histogram, strings.NewReader(expected)))
+}
+
+func TestLatencyHistogramExemplars(t *testing.T) {
+	histogram := NewLatencyHistogram(100*time.Millisecond, time.Second)
+	now := time.Unix(1000, 0)
+	histogram.now = func() time.Time { return now }
+	require.NoError(t, histogram.Observe(ResourceInfo{Verb: Get, Kind: "pods", StatusCode: http.StatusOK, Duration: 50 * time.Millisecond}))
+	require.NoError(t, histogram.Observe(ResourceInfo{
+		Verb: Get, Kind: "pods", StatusCode: http.StatusOK, Duration: 500 * time.Millisecond,
+		TraceID: "0102030405060708090a0b0c0d0e0f10", SpanID: "0102030405060708",
+	}))
+
+	ch := make(chan prometheus.Metric, 1)
+	histogram.Collect(ch)
+	var m dto.Metric
+	require.NoError(t, (<-ch).Write(&m))
+	buckets := m.GetHistogram().GetBucket()
+	require.Len(t, buckets, 2)
+	// the exemplar of the traced request is attached to its bucket
+	assert.Nil(t, buckets[0].GetExemplar())
+	exemplar := buckets[1].GetExemplar()
+	require.NotNil(t, exemplar)
+	assert.InDelta(t, 0.5, exemplar.GetValue(), 0.0001)
+	assert.True(t, now.Equal(exemplar.GetTimestamp().AsTime()))
+	labels := map[string]string{}
+	for _, l := range exemplar.GetLabel() {
+		labels[l.GetName()] = l.GetValue()
+	}
+	assert.Equal(t, map[string]string{"trace_id": "0102030405060708090a0b0c0d0e0f10", "span_id": "0102030405060708"}, labels)
+}
+
+func TestRateLimiterWrapper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	var lock sync.Mutex
+	var throttles []ThrottleInfo
+	config := NewConfig(ts.URL)
+	config.QPS = 20
+	config.Burst = 1
+	config = AddRateLimiterWrapper(config, func(info ThrottleInfo) {
+		lock.Lock()
+		defer lock.Unlock()
+		throttles = append(throttles, info)
+	})
+	lowPriority := NewClientConfig(config, ClientOptions{Name: "low-priority"})
+	assert.Same(t, config.RateLimiter.(*observedRateLimiter).RateLimiter, lowPriority.RateLimiter.(*observedRateLimiter).RateLimiter)
+
+	client := kubernetes.NewForConfigOrDie(lowPriority)
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).Get(context.Background(), "first", metav1.GetOptions{})
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).Get(context.Background(), "second", metav1.GetOptions{})
+	require.Len(t, throttles, 2)
+	assert.Less(t, throttles[0].Wait, 25*time.Millisecond)
+	assert.Greater(t, throttles[1].Wait, 25*time.Millisecond)
+	assert.Equal(t, "low-priority", throttles[1].ClientName)
+	assert.NoError(t, throttles[1].Err)
+
+	// clients overriding the QPS get their own rate limiter
+	unlimited := NewClientConfig(config, ClientOptions{Name: "unlimited", QPS: -1})
+	assert.Nil(t, unlimited.RateLimiter)
+	bulk := NewClientConfig(config, ClientOptions{Name: "bulk", QPS: 100})
+	assert.NotSame(t, config.RateLimiter.(*observedRateLimiter).RateLimiter, bulk.RateLimiter.(*observedRateLimiter).RateLimiter)
+
+	disabled := NewConfig(ts.URL)
+	disabled.QPS = -1
+	assert.Nil(t, AddRateLimiterWrapper(disabled, func(ThrottleInfo) {}).RateLimiter)
+}
+
+func TestRetryable(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	counter := NewCounter()
+	var infos []ResourceInfo
+	client := kubernetes.NewForConfigOrDie(AddMetricsTransportWrapper(NewConfig(ts.URL), func(info ResourceInfo) error {
+		infos = append(infos, info)
+		return counter.Inc(info)
+	}))
+	_, _ = client.CoreV1().Pods(metav1.NamespaceDefault).Get(context.Background(), "test", metav1.GetOptions{})
+	require.Len(t, infos, 2)
+	assert.True(t, infos[0].Retryable)
+	assert.Equal(t, http.StatusTooManyRequests, infos[0].StatusCode)
+	assert.False(t, infos[1].Retryable)
+	assert.Equal(t, int64(1), counter.Snapshot().Retryable)
+}
+
+func TestTracingTransportWrapper(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var infos []ResourceInfo
+	config := AddMetricsTransportWrapper(NewConfig(ts.URL), func(info ResourceInfo) error {
+		infos = append(infos, info)
+		return nil
+	})
+	config = AddTracingTransportWrapper(config, provider.Tracer("test"))
+	client := kubernetes.NewForConfigOrDie(config)
+	_, _ = client.AppsV1().Deployments(metav1.NamespaceDefault).GetScale(context.Background(), "test", metav1.GetOptions{})
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "Get deployments/scale", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	attrs := map[string]string{}
+	for _, attr := range span.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.Emit()
+	}
+	assert.Equal(t, "Get", attrs["k8s.request.verb"])
+	assert.Equal(t, "deployments", attrs["k8s.request.kind"])
+	assert.Equal(t, "scale", attrs["k8s.request.subresource"])
+	assert.Equal(t, metav1.NamespaceDefault, attrs["k8s.namespace.name"])
+	assert.Equal(t, "test", attrs["k8s.request.name"])
+	assert.Equal(t, "500", attrs["http.response.status_code"])
+	require.Len(t, infos, 1)
+	assert.Equal(t, span.SpanContext().SpanID().String(), infos[0].SpanID)
+}
+
+type statusRoundTripper int
+
+func (s statusRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	resp := httptest.NewRecorder()
+	resp.Code = int(s)
+	return resp.Result(), nil
+}
+
+func TestTracingTransportWrapperParsesOnce(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	var infos []ResourceInfo
+	metrics := &metricsRoundTripper{roundTripper: statusRoundTripper(http.StatusCreated), inc: func(info ResourceInfo) error {
+		infos = append(infos, info)
+		return nil
+	}}
+	rt := &tracingRoundTripper{roundTripper: metrics, tracer: provider.Tracer("test")}
+	body := `{"metadata":{"name":"test","namespace":"default"}}`
+	r := httptest.NewRequest(http.MethodPost, "https://127.0.0.1/api/v1/namespaces/default/pods", strings.NewReader(body))
+	reads := 0
+	r.GetBody = func() (io.ReadCloser, error) {
+		reads++
+		return io.NopCloser(strings.NewReader(body)), nil
+	}
+	_, err := rt.RoundTrip(r)
+	require.NoError(t, err)
+	// the body of created objects is only read by the outer wrapper
+	assert.Equal(t, 1, reads)
+	require.Len(t, infos, 1)
+	assert.Equal(t, "test", infos[0].Name)
+	assert.Equal(t, http.StatusCreated, infos[0].StatusCode)
+	assert.NotEmpty(t, infos[0].SpanID)
+}
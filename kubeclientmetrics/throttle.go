@@ -0,0 +1,87 @@
+package kubeclientmetrics
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+// ThrottleInfo describes the wait of a request for the client-side rate limiter
+type ThrottleInfo struct {
+	// ClientName is the name of the logical client which made the request, see NewClientConfig
+	ClientName string
+	Wait       time.Duration
+	// Err is set if the context of the request was done before the rate limiter admitted it
+	Err error
+}
+
+// AddRateLimiterWrapper wraps the client-side rate limiter of the config, so that onThrottle is
+// called with the time every request waited for it. This tells client throttling apart from server
+// latency, which is reported as ResourceInfo.Duration. If the config has no rate limiter, one is
+// created from QPS and Burst in the same way as client-go does. Unlike the one created by client-go,
+// it is shared by all clients created from the config, or from copies made with NewClientConfig
+// which do not override QPS or Burst. Configs with rate limiting disabled are returned unchanged.
+func AddRateLimiterWrapper(config *rest.Config, onThrottle func(ThrottleInfo)) *rest.Config {
+	limiter := config.RateLimiter
+	if limiter == nil {
+		limiter = newRateLimiter(config.QPS, config.Burst)
+		if limiter == nil {
+			return config
+		}
+	}
+	config.RateLimiter = &observedRateLimiter{RateLimiter: limiter, onThrottle: onThrottle}
+	return config
+}
+
+// newRateLimiter returns the rate limiter client-go would create, or nil if rate limiting is
+// disabled
+func newRateLimiter(qps float32, burst int) flowcontrol.RateLimiter {
+	if qps == 0 {
+		qps = rest.DefaultQPS
+	}
+	if burst == 0 {
+		burst = rest.DefaultBurst
+	}
+	if qps < 0 {
+		return nil
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+}
+
+type observedRateLimiter struct {
+	flowcontrol.RateLimiter
+	clientName string
+	onThrottle func(ThrottleInfo)
+}
+
+func (l *observedRateLimiter) Accept() {
+	start := time.Now()
+	l.RateLimiter.Accept()
+	l.onThrottle(ThrottleInfo{ClientName: l.clientName, Wait: time.Since(start)})
+}
+
+func (l *observedRateLimiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	err := l.RateLimiter.Wait(ctx)
+	name := clientNameFrom(ctx)
+	if name == "" {
+		name = l.clientName
+	}
+	l.onThrottle(ThrottleInfo{ClientName: name, Wait: time.Since(start), Err: err})
+	return err
+}
+
+// forClient returns the rate limiter for a copy of the config made by NewClientConfig. The rate
+// limiter is only shared with the base config if the copy does not override QPS or Burst.
+func (l *observedRateLimiter) forClient(c *rest.Config, opts ClientOptions) flowcontrol.RateLimiter {
+	limiter := l.RateLimiter
+	if opts.QPS != 0 || opts.Burst != 0 {
+		limiter = newRateLimiter(c.QPS, c.Burst)
+		if limiter == nil {
+			return nil
+		}
+	}
+	return &observedRateLimiter{RateLimiter: limiter, clientName: opts.Name, onThrottle: l.onThrottle}
+}
@@ -0,0 +1,91 @@
+package kubeclientmetrics
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+
+	"github.com/argoproj/pkg/v2/tracing"
+)
+
+// AddTracingTransportWrapper adds a transport wrapper which creates a span for every request to
+// the Kubernetes API, with the attributes reported to the metrics transport wrapper. If tracer is
+// nil, tracing.Tracer is used. Call it after AddMetricsTransportWrapper, so that
+// ResourceInfo.TraceID and SpanID identify the span of the request, and the request is only parsed
+// once.
+func AddTracingTransportWrapper(config *rest.Config, tracer trace.Tracer) *rest.Config {
+	if tracer == nil {
+		tracer = tracing.Tracer()
+	}
+	wrap := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return &tracingRoundTripper{roundTripper: rt, tracer: tracer}
+	}
+	return config
+}
+
+type tracingRoundTripper struct {
+	roundTripper http.RoundTripper
+	tracer       trace.Tracer
+}
+
+// resourceInfoKey is the context key of the ResourceInfo parsed by the tracing transport wrapper,
+// so that the metrics transport wrapper below it does not parse the request again
+type resourceInfoKey struct{}
+
+// requestInfo returns the ResourceInfo of the request, parsed by an outer transport wrapper if any
+func requestInfo(r *http.Request) ResourceInfo {
+	if info, ok := r.Context().Value(resourceInfoKey{}).(ResourceInfo); ok {
+		return info
+	}
+	return parseRequest(r)
+}
+
+func (t *tracingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	info := requestInfo(r)
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.ServerAddress(r.URL.Hostname()),
+		attribute.String("k8s.request.verb", string(info.Verb)),
+		attribute.String("k8s.request.kind", info.Kind),
+	}
+	for key, value := range map[string]string{
+		"k8s.request.subresource": info.Subresource,
+		"k8s.namespace.name":      info.Namespace,
+		"k8s.request.name":        info.Name,
+		"k8s.client.name":         clientNameFrom(r.Context()),
+	} {
+		if value != "" {
+			attrs = append(attrs, attribute.String(key, value))
+		}
+	}
+	name := string(info.Verb) + " " + info.Kind
+	if info.Subresource != "" {
+		name += "/" + info.Subresource
+	}
+	ctx, span := t.tracer.Start(r.Context(), name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+	ctx = context.WithValue(ctx, resourceInfoKey{}, info)
+	resp, err := t.roundTripper.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if retryAfter, ok := retryAfter(resp); ok {
+		span.SetAttributes(attribute.Int64("k8s.request.retry_after_seconds", int64(retryAfter.Seconds())))
+	}
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
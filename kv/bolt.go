@@ -0,0 +1,142 @@
+package kv
+
+import (
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltStore struct {
+	db  *bolt.DB
+	now func() time.Time
+}
+
+// NewBoltStore opens, or creates, a store persisted in the given file
+func NewBoltStore(path string) (Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &boltStore{db: db, now: time.Now}, nil
+}
+
+func (s *boltStore) Get(bucket, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return ErrNotFound
+		}
+		buf := b.Get([]byte(key))
+		if buf == nil {
+			return ErrNotFound
+		}
+		v, expired, err := decode(buf, s.now())
+		if err != nil {
+			return err
+		}
+		if expired {
+			return ErrNotFound
+		}
+		value = v
+		return nil
+	})
+	return value, err
+}
+
+func (s *boltStore) Put(bucket, key string, value []byte, ttl time.Duration) error {
+	if err := checkKey(bucket, key); err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), encode(value, ttl, s.now()))
+	})
+}
+
+func (s *boltStore) Delete(bucket, key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+func (s *boltStore) Iterate(bucket string, fn func(key string, value []byte) error) error {
+	var keys []string
+	var values [][]byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		now := s.now()
+		return b.ForEach(func(k, buf []byte) error {
+			v, expired, err := decode(buf, now)
+			if err != nil {
+				return err
+			}
+			if !expired {
+				keys = append(keys, string(k))
+				values = append(values, v)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// fn is called outside of the transaction so that it may modify the store, which would
+	// otherwise deadlock
+	for i, k := range keys {
+		if err := fn(k, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *boltStore) PurgeExpired() (int, error) {
+	purged := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		now := s.now()
+		return tx.ForEach(func(_ []byte, b *bolt.Bucket) error {
+			var expiredKeys [][]byte
+			err := b.ForEach(func(k, buf []byte) error {
+				_, expired, err := decode(buf, now)
+				if err != nil {
+					return err
+				}
+				if expired {
+					expiredKeys = append(expiredKeys, append([]byte{}, k...))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, k := range expiredKeys {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+			purged += len(expiredKeys)
+			return nil
+		})
+	})
+	return purged, err
+}
+
+func (s *boltStore) Close() error {
+	if err := s.db.Close(); err != nil && !errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package kv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned by Get when the key does not exist or has expired
+	ErrNotFound = errors.New("key not found")
+	// ErrEmptyKey is returned by Put for an empty bucket or key
+	ErrEmptyKey = errors.New("empty bucket or key")
+)
+
+// Store is a namespaced key-value store. Keys live in buckets, which are created on first write.
+type Store interface {
+	// Get returns the value of the key, or ErrNotFound
+	Get(bucket, key string) ([]byte, error)
+	// Put sets the value of the key. A positive ttl makes the key expire after the given duration.
+	// Bucket and key must not be empty.
+	Put(bucket, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key. Deleting a missing key is not an error.
+	Delete(bucket, key string) error
+	// Iterate calls fn for every unexpired key of the bucket in key order, as of the start of the
+	// iteration. fn may use the store. Iteration stops at the first error returned by fn, which is
+	// returned by Iterate.
+	Iterate(bucket string, fn func(key string, value []byte) error) error
+	// PurgeExpired removes all expired keys and returns how many were removed
+	PurgeExpired() (int, error)
+	Close() error
+}
+
+func checkKey(bucket, key string) error {
+	if bucket == "" || key == "" {
+		return fmt.Errorf("%w: bucket %q, key %q", ErrEmptyKey, bucket, key)
+	}
+	return nil
+}
+
+// headerLen is the length of the expiry timestamp stored in front of every value
+const headerLen = 8
+
+func encode(value []byte, ttl time.Duration, now time.Time) []byte {
+	var expiry int64
+	if ttl > 0 {
+		expiry = now.Add(ttl).UnixNano()
+	}
+	buf := make([]byte, headerLen+len(value))
+	binary.BigEndian.PutUint64(buf, uint64(expiry))
+	copy(buf[headerLen:], value)
+	return buf
+}
+
+// decode returns the value stored in buf and whether it has expired
+func decode(buf []byte, now time.Time) ([]byte, bool, error) {
+	if len(buf) < headerLen {
+		return nil, false, fmt.Errorf("corrupt value of length %d", len(buf))
+	}
+	expiry := int64(binary.BigEndian.Uint64(buf))
+	expired := expiry != 0 && now.UnixNano() >= expiry
+	value := make([]byte, len(buf)-headerLen)
+	copy(value, buf[headerLen:])
+	return value, expired, nil
+}
@@ -0,0 +1,139 @@
+package kv
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newStores(t *testing.T) map[string]func(clock *fakeClock) Store {
+	return map[string]func(clock *fakeClock) Store{
+		"memory": func(clock *fakeClock) Store {
+			s := NewMemoryStore()
+			s.(*memoryStore).now = clock.Now
+			return s
+		},
+		"bolt": func(clock *fakeClock) Store {
+			s, err := NewBoltStore(filepath.Join(t.TempDir(), "state.db"))
+			require.NoError(t, err)
+			s.(*boltStore).now = clock.Now
+			return s
+		},
+	}
+}
+
+func TestStore(t *testing.T) {
+	for name, newStore := range newStores(t) {
+		t.Run(name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Now()}
+			s := newStore(clock)
+			defer func() { require.NoError(t, s.Close()) }()
+
+			_, err := s.Get("tokens", "missing")
+			require.ErrorIs(t, err, ErrNotFound)
+
+			require.NoError(t, s.Put("tokens", "b", []byte("2"), 0))
+			require.NoError(t, s.Put("tokens", "a", []byte("1"), 0))
+			require.NoError(t, s.Put("other", "a", []byte("other"), 0))
+			value, err := s.Get("tokens", "a")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("1"), value)
+
+			var keys []string
+			require.NoError(t, s.Iterate("tokens", func(key string, value []byte) error {
+				keys = append(keys, key+"="+string(value))
+				return nil
+			}))
+			assert.Equal(t, []string{"a=1", "b=2"}, keys)
+
+			// the store may be modified while iterating
+			require.NoError(t, s.Iterate("tokens", func(key string, value []byte) error {
+				return s.Put("tokens", key, append(value, '0'), 0)
+			}))
+			value, err = s.Get("tokens", "b")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("20"), value)
+
+			require.ErrorIs(t, s.Put("tokens", "", []byte("1"), 0), ErrEmptyKey)
+			require.ErrorIs(t, s.Put("", "a", []byte("1"), 0), ErrEmptyKey)
+			_, err = s.Get("tokens", "")
+			require.ErrorIs(t, err, ErrNotFound)
+			require.NoError(t, s.Delete("tokens", ""))
+
+			stop := errors.New("stop")
+			err = s.Iterate("tokens", func(key string, value []byte) error { return stop })
+			require.ErrorIs(t, err, stop)
+			require.NoError(t, s.Iterate("missing", func(key string, value []byte) error { return stop }))
+
+			require.NoError(t, s.Delete("tokens", "a"))
+			require.NoError(t, s.Delete("tokens", "a"))
+			require.NoError(t, s.Delete("missing", "a"))
+			_, err = s.Get("tokens", "a")
+			require.ErrorIs(t, err, ErrNotFound)
+			value, err = s.Get("other", "a")
+			require.NoError(t, err)
+			assert.Equal(t, []byte("other"), value)
+		})
+	}
+}
+
+func TestStoreTTL(t *testing.T) {
+	for name, newStore := range newStores(t) {
+		t.Run(name, func(t *testing.T) {
+			clock := &fakeClock{now: time.Now()}
+			s := newStore(clock)
+			defer func() { require.NoError(t, s.Close()) }()
+
+			require.NoError(t, s.Put("cache", "short", []byte("1"), time.Minute))
+			require.NoError(t, s.Put("cache", "long", []byte("2"), time.Hour))
+			require.NoError(t, s.Put("cache", "forever", []byte("3"), 0))
+
+			clock.now = clock.now.Add(2 * time.Minute)
+			_, err := s.Get("cache", "short")
+			require.ErrorIs(t, err, ErrNotFound)
+			_, err = s.Get("cache", "long")
+			require.NoError(t, err)
+
+			count := 0
+			require.NoError(t, s.Iterate("cache", func(key string, value []byte) error {
+				count++
+				return nil
+			}))
+			assert.Equal(t, 2, count)
+
+			clock.now = clock.now.Add(2 * time.Hour)
+			purged, err := s.PurgeExpired()
+			require.NoError(t, err)
+			assert.Equal(t, 2, purged)
+			_, err = s.Get("cache", "forever")
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestBoltStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	s, err := NewBoltStore(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Put("resume", "watch", []byte("12345"), 0))
+	require.NoError(t, s.Close())
+
+	s, err = NewBoltStore(path)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, s.Close()) }()
+	value, err := s.Get("resume", "watch")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("12345"), value)
+}
@@ -0,0 +1,113 @@
+package kv
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	lock    sync.RWMutex
+	buckets map[string]map[string][]byte
+	now     func() time.Time
+}
+
+// NewMemoryStore returns a store which keeps everything in memory. It is intended for tests.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: map[string]map[string][]byte{},
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStore) Get(bucket, key string) ([]byte, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	buf, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	value, expired, err := decode(buf, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *memoryStore) Put(bucket, key string, value []byte, ttl time.Duration) error {
+	if err := checkKey(bucket, key); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	b, ok := s.buckets[bucket]
+	if !ok {
+		b = map[string][]byte{}
+		s.buckets[bucket] = b
+	}
+	b[key] = encode(value, ttl, s.now())
+	return nil
+}
+
+func (s *memoryStore) Delete(bucket, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.buckets[bucket], key)
+	return nil
+}
+
+func (s *memoryStore) Iterate(bucket string, fn func(key string, value []byte) error) error {
+	s.lock.RLock()
+	b := s.buckets[bucket]
+	keys := make([]string, 0, len(b))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make(map[string][]byte, len(keys))
+	now := s.now()
+	for _, k := range keys {
+		value, expired, err := decode(b[k], now)
+		if err != nil {
+			s.lock.RUnlock()
+			return err
+		}
+		if !expired {
+			entries[k] = value
+		}
+	}
+	s.lock.RUnlock()
+
+	// fn is called without holding the lock so that it may modify the store
+	for _, k := range keys {
+		if value, ok := entries[k]; ok {
+			if err := fn(k, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) PurgeExpired() (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	purged := 0
+	now := s.now()
+	for _, b := range s.buckets {
+		for k, buf := range b {
+			if _, expired, _ := decode(buf, now); expired {
+				delete(b, k)
+				purged++
+			}
+		}
+	}
+	return purged, nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,188 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the severity of a log entry
+type Level int32
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	default:
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+}
+
+// ParseLevel parses a level name such as "info" or "warning"
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	default:
+		return 0, fmt.Errorf("invalid log level '%s'", s)
+	}
+}
+
+// MarshalText encodes the level as its name
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a level name
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// LevelVar is a level which can be changed at runtime, and shared by many loggers
+type LevelVar struct {
+	level atomic.Int32
+}
+
+// NewLevelVar returns a LevelVar set to the given level
+func NewLevelVar(level Level) *LevelVar {
+	lv := &LevelVar{}
+	lv.Set(level)
+	return lv
+}
+
+func (lv *LevelVar) Level() Level {
+	return Level(lv.level.Load())
+}
+
+func (lv *LevelVar) Set(level Level) {
+	lv.level.Store(int32(level))
+}
+
+// Fields are key/value pairs attached to log entries
+type Fields map[string]interface{}
+
+// Sink writes log entries to a logging backend
+type Sink interface {
+	Log(level Level, msg string, fields Fields)
+}
+
+// LevelFilter may be implemented by sinks which discard entries below some level, so that such
+// entries are not even formatted
+type LevelFilter interface {
+	Enabled(level Level) bool
+}
+
+// Logger logs messages with accumulated fields
+type Logger interface {
+	WithField(key string, value interface{}) Logger
+	WithFields(fields Fields) Logger
+	WithError(err error) Logger
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// New returns a logger which writes to the sink. Entries below the level are discarded. If level is
+// nil, filtering is left to the backend.
+func New(sink Sink, level *LevelVar) Logger {
+	return &logger{sink: sink, level: level}
+}
+
+type logger struct {
+	sink   Sink
+	level  *LevelVar
+	fields Fields
+}
+
+func (l *logger) WithField(key string, value interface{}) Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+func (l *logger) WithFields(fields Fields) Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &logger{sink: l.sink, level: l.level, fields: merged}
+}
+
+func (l *logger) WithError(err error) Logger {
+	return l.WithField("error", err)
+}
+
+func (l *logger) Debugf(format string, args ...interface{}) {
+	l.log(DebugLevel, format, args)
+}
+
+func (l *logger) Infof(format string, args ...interface{}) {
+	l.log(InfoLevel, format, args)
+}
+
+func (l *logger) Warnf(format string, args ...interface{}) {
+	l.log(WarnLevel, format, args)
+}
+
+func (l *logger) Errorf(format string, args ...interface{}) {
+	l.log(ErrorLevel, format, args)
+}
+
+func (l *logger) log(level Level, format string, args []interface{}) {
+	if l.level != nil && level < l.level.Level() {
+		return
+	}
+	if filter, ok := l.sink.(LevelFilter); ok && !filter.Enabled(level) {
+		return
+	}
+	l.sink.Log(level, fmt.Sprintf(format, args...), l.fields)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx which carries the logger
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or a logger writing to the standard logrus logger
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(Logger); ok {
+			return l
+		}
+	}
+	return defaultLogger
+}
+
+// WithFields returns a copy of ctx whose logger has the additional fields
+func WithFields(ctx context.Context, fields Fields) context.Context {
+	return NewContext(ctx, FromContext(ctx).WithFields(fields))
+}
@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type entry struct {
+	level  Level
+	msg    string
+	fields Fields
+}
+
+type fakeSink struct {
+	entries []entry
+}
+
+func (s *fakeSink) Log(level Level, msg string, fields Fields) {
+	s.entries = append(s.entries, entry{level, msg, fields})
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARNING")
+	require.NoError(t, err)
+	assert.Equal(t, WarnLevel, level)
+	assert.Equal(t, "warn", level.String())
+	_, err = ParseLevel("verbose")
+	require.Error(t, err)
+}
+
+func TestFieldsAndLevel(t *testing.T) {
+	sink := &fakeSink{}
+	level := NewLevelVar(InfoLevel)
+	logger := New(sink, level).WithField("a", 1)
+	child := logger.WithFields(Fields{"b": 2}).WithError(errors.New("boom"))
+
+	child.Debugf("hidden")
+	child.Infof("hello %s", "world")
+	logger.Warnf("parent")
+	level.Set(DebugLevel)
+	child.Debugf("visible")
+
+	assert.Equal(t, []entry{
+		{InfoLevel, "hello world", Fields{"a": 1, "b": 2, "error": errors.New("boom")}},
+		{WarnLevel, "parent", Fields{"a": 1}},
+		{DebugLevel, "visible", Fields{"a": 1, "b": 2, "error": errors.New("boom")}},
+	}, sink.entries)
+}
+
+type formatCounter struct {
+	calls int
+}
+
+func (c *formatCounter) String() string {
+	c.calls++
+	return "formatted"
+}
+
+func TestBackendLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := log.New()
+	l.SetOutput(&buf)
+	l.SetLevel(log.WarnLevel)
+	core, logs := observer.New(zap.WarnLevel)
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})
+	for _, logger := range []Logger{NewLogrus(l, nil), NewLogrus(l.WithField("a", 1), nil), NewZap(zap.New(core), nil), NewSlog(slog.New(handler), nil)} {
+		// messages discarded by the backend are not formatted
+		counter := &formatCounter{}
+		logger.Infof("%s", counter)
+		assert.Equal(t, 0, counter.calls)
+		logger.Warnf("%s", counter)
+		assert.Equal(t, 1, counter.calls)
+	}
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, defaultLogger, FromContext(context.Background()))
+
+	sink := &fakeSink{}
+	ctx := NewContext(context.Background(), New(sink, nil))
+	ctx = WithFields(ctx, Fields{"request": "123"})
+	FromContext(ctx).Errorf("failed")
+	assert.Equal(t, []entry{{ErrorLevel, "failed", Fields{"request": "123"}}}, sink.entries)
+}
+
+func TestLogrus(t *testing.T) {
+	var buf bytes.Buffer
+	l := log.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(&log.TextFormatter{DisableTimestamp: true})
+	NewLogrus(l, nil).WithField("key", "value").Infof("hello")
+	assert.Equal(t, "level=info msg=hello key=value\n", buf.String())
+}
+
+func TestSlog(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	NewSlog(slog.New(handler), nil).WithFields(Fields{"b": 2, "a": 1}).Warnf("hello")
+	assert.Equal(t, "level=WARN msg=hello a=1 b=2\n", buf.String())
+}
+
+func TestZap(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	NewZap(zap.New(core), NewLevelVar(InfoLevel)).WithField("key", "value").Infof("hello")
+	NewZap(zap.New(core), NewLevelVar(InfoLevel)).Debugf("hidden")
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, "hello", logs.All()[0].Message)
+	assert.Equal(t, map[string]interface{}{"key": "value"}, logs.All()[0].ContextMap())
+}
+
+func TestRingBuffer(t *testing.T) {
+	rb := NewRingBuffer(3)
+	logger := New(rb, nil)
+	assert.Empty(t, rb.Entries())
+	logger.Infof("one")
+	logger.WithError(errors.New("boom")).Errorf("two")
+
+	l := log.New()
+	l.SetOutput(io.Discard)
+	l.AddHook(rb.LogrusHook())
+	l.WithField("key", "value").Warn("three")
+
+	slog.New(rb.SlogHandler(nil)).WithGroup("g").With("a", 1).Debug("four", slog.Group("h", "b", 2))
+
+	var messages []string
+	for _, e := range rb.Entries() {
+		messages = append(messages, e.Message)
+	}
+	assert.Equal(t, []string{"two", "three", "four"}, messages)
+	entries := rb.Entries()
+	assert.Equal(t, Fields{"error": "boom"}, entries[0].Fields)
+	assert.Equal(t, WarnLevel, entries[1].Level)
+	assert.Equal(t, Fields{"key": "value"}, entries[1].Fields)
+	assert.Equal(t, DebugLevel, entries[2].Level)
+	assert.Equal(t, Fields{"g.a": int64(1), "g.h.b": int64(2)}, entries[2].Fields)
+
+	w := httptest.NewRecorder()
+	rb.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/logs", nil))
+	var decoded []Entry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+	require.Len(t, decoded, 3)
+	assert.Equal(t, ErrorLevel, decoded[0].Level)
+	assert.Contains(t, w.Body.String(), `"level":"error"`)
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+var defaultLogger = NewLogrus(log.StandardLogger(), nil)
+
+type logrusSink struct {
+	logger log.FieldLogger
+}
+
+// NewLogrus returns a logger writing to a logrus logger or entry
+func NewLogrus(l log.FieldLogger, level *LevelVar) Logger {
+	return New(&logrusSink{logger: l}, level)
+}
+
+func (s *logrusSink) Enabled(level Level) bool {
+	switch l := s.logger.(type) {
+	case *log.Logger:
+		return l.IsLevelEnabled(logrusLevel(level))
+	case *log.Entry:
+		return l.Logger.IsLevelEnabled(logrusLevel(level))
+	default:
+		return true
+	}
+}
+
+func (s *logrusSink) Log(level Level, msg string, fields Fields) {
+	entry := s.logger.WithFields(log.Fields(fields))
+	switch level {
+	case DebugLevel:
+		entry.Debug(msg)
+	case InfoLevel:
+		entry.Info(msg)
+	case WarnLevel:
+		entry.Warn(msg)
+	default:
+		entry.Error(msg)
+	}
+}
+
+func logrusLevel(level Level) log.Level {
+	switch level {
+	case DebugLevel:
+		return log.DebugLevel
+	case InfoLevel:
+		return log.InfoLevel
+	case WarnLevel:
+		return log.WarnLevel
+	default:
+		return log.ErrorLevel
+	}
+}
@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Entry is a log entry captured by a RingBuffer
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Level   Level     `json:"level"`
+	Message string    `json:"msg"`
+	Fields  Fields    `json:"fields,omitempty"`
+}
+
+// RingBuffer keeps the most recent log entries in memory, so that what happened just before a
+// failure can be inspected without external log infrastructure. It is safe for concurrent use.
+//
+// A RingBuffer is a Sink, and can capture the entries of logrus and slog loggers through
+// LogrusHook and SlogHandler.
+type RingBuffer struct {
+	lock    sync.Mutex
+	entries []Entry
+	next    int
+	full    bool
+}
+
+// NewRingBuffer returns a RingBuffer keeping the last size entries
+func NewRingBuffer(size int) *RingBuffer {
+	return &RingBuffer{entries: make([]Entry, max(size, 1))}
+}
+
+// Add appends an entry, overwriting the oldest one if the buffer is full. Error values among the
+// fields are replaced by their message, so that entries can be encoded as JSON.
+func (rb *RingBuffer) Add(e Entry) {
+	if len(e.Fields) > 0 {
+		fields := make(Fields, len(e.Fields))
+		for k, v := range e.Fields {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			fields[k] = v
+		}
+		e.Fields = fields
+	}
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+	rb.entries[rb.next] = e
+	rb.next = (rb.next + 1) % len(rb.entries)
+	if rb.next == 0 {
+		rb.full = true
+	}
+}
+
+// Log implements Sink
+func (rb *RingBuffer) Log(level Level, msg string, fields Fields) {
+	rb.Add(Entry{Time: time.Now(), Level: level, Message: msg, Fields: fields})
+}
+
+// Entries returns the captured entries, oldest first
+func (rb *RingBuffer) Entries() []Entry {
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+	if !rb.full {
+		return append([]Entry(nil), rb.entries[:rb.next]...)
+	}
+	return append(append([]Entry(nil), rb.entries[rb.next:]...), rb.entries[:rb.next]...)
+}
+
+// Handler returns an HTTP handler which serves the captured entries as a JSON array
+func (rb *RingBuffer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(rb.Entries())
+	})
+}
+
+// LogrusHook returns a hook which captures the entries of a logrus logger, e.g.
+// log.AddHook(rb.LogrusHook())
+func (rb *RingBuffer) LogrusHook() log.Hook {
+	return &ringBufferHook{rb: rb}
+}
+
+type ringBufferHook struct {
+	rb *RingBuffer
+}
+
+func (h *ringBufferHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *ringBufferHook) Fire(e *log.Entry) error {
+	level := ErrorLevel
+	switch e.Level {
+	case log.TraceLevel, log.DebugLevel:
+		level = DebugLevel
+	case log.InfoLevel:
+		level = InfoLevel
+	case log.WarnLevel:
+		level = WarnLevel
+	}
+	h.rb.Add(Entry{Time: e.Time, Level: level, Message: e.Message, Fields: Fields(e.Data)})
+	return nil
+}
+
+// SlogHandler returns a slog handler which captures records before passing them to next. If next
+// is nil, records of all levels are captured and otherwise discarded.
+func (rb *RingBuffer) SlogHandler(next slog.Handler) slog.Handler {
+	return &ringBufferHandler{rb: rb, next: next}
+}
+
+type ringBufferHandler struct {
+	rb     *RingBuffer
+	next   slog.Handler
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *ringBufferHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next == nil || h.next.Enabled(ctx, level)
+}
+
+func (h *ringBufferHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := Fields{}
+	for _, a := range h.attrs {
+		addAttr(fields, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.prefix, a)
+		return true
+	})
+	level := ErrorLevel
+	switch {
+	case r.Level < slog.LevelInfo:
+		level = DebugLevel
+	case r.Level < slog.LevelWarn:
+		level = InfoLevel
+	case r.Level < slog.LevelError:
+		level = WarnLevel
+	}
+	h.rb.Add(Entry{Time: r.Time, Level: level, Message: r.Message, Fields: fields})
+	if h.next == nil {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *ringBufferHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		// qualify the key now, as later groups do not apply to these attributes
+		clone.attrs = append(clone.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	if h.next != nil {
+		clone.next = h.next.WithAttrs(attrs)
+	}
+	return &clone
+}
+
+func (h *ringBufferHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	if h.next != nil {
+		clone.next = h.next.WithGroup(name)
+	}
+	return &clone
+}
+
+// addAttr adds the attribute to fields, flattening groups into dotted keys
+func addAttr(fields Fields, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			addAttr(fields, p, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fields[prefix+a.Key] = v.Any()
+}
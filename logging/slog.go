@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+type slogSink struct {
+	logger *slog.Logger
+}
+
+// NewSlog returns a logger writing to a slog logger
+func NewSlog(l *slog.Logger, level *LevelVar) Logger {
+	return New(&slogSink{logger: l}, level)
+}
+
+func (s *slogSink) Enabled(level Level) bool {
+	return s.logger.Enabled(context.Background(), slogLevel(level))
+}
+
+func (s *slogSink) Log(level Level, msg string, fields Fields) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	s.logger.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+}
+
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
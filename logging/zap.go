@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type zapSink struct {
+	logger *zap.Logger
+}
+
+// NewZap returns a logger writing to a zap logger
+func NewZap(l *zap.Logger, level *LevelVar) Logger {
+	return New(&zapSink{logger: l}, level)
+}
+
+func (s *zapSink) Enabled(level Level) bool {
+	return s.logger.Core().Enabled(zapLevel(level))
+}
+
+func (s *zapSink) Log(level Level, msg string, fields Fields) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	zapFields := make([]zap.Field, 0, len(keys))
+	for _, k := range keys {
+		zapFields = append(zapFields, zap.Any(k, fields[k]))
+	}
+	if ce := s.logger.Check(zapLevel(level), msg); ce != nil {
+		ce.Write(zapFields...)
+	}
+}
+
+func zapLevel(level Level) zapcore.Level {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case InfoLevel:
+		return zapcore.InfoLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
@@ -0,0 +1,132 @@
+package pager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultPageSize = 500
+)
+
+// Request asks for a page of at most Limit items, starting at the continue token returned with the
+// previous page. Continue is empty for the first page.
+type Request struct {
+	Limit    int64
+	Continue string
+}
+
+// Page is a page of items. Continue is the token for the next page, or empty for the last page.
+type Page[T any] struct {
+	Items    []T
+	Continue string
+}
+
+// FetchFunc fetches a page of items
+type FetchFunc[T any] func(ctx context.Context, req Request) (Page[T], error)
+
+// HandleFunc handles a single item
+type HandleFunc[T any] func(ctx context.Context, item T) error
+
+// Options configures Each. Zero values are replaced by the defaults above.
+type Options struct {
+	// PageSize is the maximum number of items requested per page
+	PageSize int64
+	// ContinueOnError keeps handling the remaining items after the handler failed, and reports all
+	// failures in a PartialError at the end. Errors fetching a page always stop the iteration.
+	ContinueOnError bool
+}
+
+// ItemError is the failure to handle an item
+type ItemError struct {
+	// Index is the position of the item across all pages
+	Index int
+	Err   error
+}
+
+func (e ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e ItemError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned by Each with Options.ContinueOnError when some items could not be
+// handled
+type PartialError struct {
+	// Handled is the number of items handled successfully
+	Handled int
+	Failed  []ItemError
+}
+
+func (e *PartialError) Error() string {
+	messages := make([]string, len(e.Failed))
+	for i, failed := range e.Failed {
+		messages[i] = failed.Error()
+	}
+	return fmt.Sprintf("failed to handle %d of %d items: %s", len(e.Failed), e.Handled+len(e.Failed), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the errors of the failed items, so that errors.Is and errors.As match any of them
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, failed := range e.Failed {
+		errs[i] = failed
+	}
+	return errs
+}
+
+// Each fetches all pages and calls handle for every item in order. It stops at the first error
+// unless Options.ContinueOnError is set, or when the context is cancelled.
+func Each[T any](ctx context.Context, fetch FetchFunc[T], handle HandleFunc[T], opts Options) error {
+	if opts.PageSize == 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	partial := &PartialError{}
+	req := Request{Limit: opts.PageSize}
+	index := 0
+	for pages := 1; ; pages++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page, err := fetch(ctx, req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch page %d: %w", pages, err)
+		}
+		for _, item := range page.Items {
+			if err := handle(ctx, item); err != nil {
+				if !opts.ContinueOnError || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+					return err
+				}
+				partial.Failed = append(partial.Failed, ItemError{Index: index, Err: err})
+			} else {
+				partial.Handled++
+			}
+			index++
+		}
+		if page.Continue == "" {
+			break
+		}
+		if page.Continue == req.Continue {
+			return fmt.Errorf("continue token %q was returned twice", page.Continue)
+		}
+		req.Continue = page.Continue
+	}
+	if len(partial.Failed) > 0 {
+		return partial
+	}
+	return nil
+}
+
+// Collect fetches all pages and returns the items
+func Collect[T any](ctx context.Context, fetch FetchFunc[T], opts Options) ([]T, error) {
+	var items []T
+	err := Each(ctx, fetch, func(_ context.Context, item T) error {
+		items = append(items, item)
+		return nil
+	}, opts)
+	return items, err
+}
@@ -0,0 +1,83 @@
+package pager
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fetchInts pages through the integers 0 to n-1
+func fetchInts(n int, requests *[]Request) FetchFunc[int] {
+	return func(_ context.Context, req Request) (Page[int], error) {
+		*requests = append(*requests, req)
+		start := 0
+		if req.Continue != "" {
+			start, _ = strconv.Atoi(req.Continue)
+		}
+		var page Page[int]
+		for i := start; i < n && len(page.Items) < int(req.Limit); i++ {
+			page.Items = append(page.Items, i)
+		}
+		if end := start + len(page.Items); end < n {
+			page.Continue = strconv.Itoa(end)
+		}
+		return page, nil
+	}
+}
+
+func TestEach(t *testing.T) {
+	var requests []Request
+	items, err := Collect(context.Background(), fetchInts(5, &requests), Options{PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, items)
+	assert.Equal(t, []Request{{Limit: 2}, {Limit: 2, Continue: "2"}, {Limit: 2, Continue: "4"}}, requests)
+
+	requests = nil
+	_, err = Collect(context.Background(), fetchInts(5, &requests), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []Request{{Limit: DefaultPageSize}}, requests)
+}
+
+func TestEachErrors(t *testing.T) {
+	errOdd := errors.New("odd")
+	handle := func(_ context.Context, i int) error {
+		if i%2 == 1 {
+			return errOdd
+		}
+		return nil
+	}
+
+	var requests []Request
+	err := Each(context.Background(), fetchInts(5, &requests), handle, Options{PageSize: 2})
+	require.ErrorIs(t, err, errOdd)
+	assert.Len(t, requests, 1)
+
+	err = Each(context.Background(), fetchInts(5, &requests), handle, Options{PageSize: 2, ContinueOnError: true})
+	var partial *PartialError
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, 3, partial.Handled)
+	assert.Equal(t, []ItemError{{Index: 1, Err: errOdd}, {Index: 3, Err: errOdd}}, partial.Failed)
+	require.ErrorIs(t, err, errOdd)
+	assert.EqualError(t, err, "failed to handle 2 of 5 items: item 1: odd; item 3: odd")
+
+	failing := func(context.Context, Request) (Page[int], error) {
+		return Page[int]{}, errors.New("unavailable")
+	}
+	err = Each(context.Background(), failing, handle, Options{ContinueOnError: true})
+	require.EqualError(t, err, "failed to fetch page 1: unavailable")
+
+	loop := func(context.Context, Request) (Page[int], error) {
+		return Page[int]{Continue: "same"}, nil
+	}
+	err = Each(context.Background(), loop, handle, Options{})
+	require.EqualError(t, err, `continue token "same" was returned twice`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Each(ctx, fetchInts(5, &requests), handle, Options{})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,196 @@
+package pipeline
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/argoproj/pkg/v2/digest"
+	"github.com/argoproj/pkg/v2/ioutil"
+	"github.com/argoproj/pkg/v2/progress"
+)
+
+// Pipeline streams data from a source to a sink through a sequence of stages, e.g.
+//
+//	result, err := pipeline.New(f).Hash(digest.SHA256).Gzip().RateLimit(10 << 20).To(ctx, w)
+//
+// Stages are applied in the order they are added. A pipeline can only be run once.
+type Pipeline struct {
+	src    io.Reader
+	stages []stage
+}
+
+// Result describes a completed run
+type Result struct {
+	// Bytes is the number of bytes written to the sink
+	Bytes int64
+	// Digests holds the digests computed by the Hash stages, in stage order
+	Digests []digest.Digest
+}
+
+// run holds the state of a single run
+type run struct {
+	ctx     context.Context
+	closers []io.Closer
+	finish  []func() error
+	result  Result
+}
+
+type stage func(r *run, src io.Reader) (io.Reader, error)
+
+// New returns a pipeline reading from src
+func New(src io.Reader) *Pipeline {
+	return &Pipeline{src: src}
+}
+
+func (p *Pipeline) add(s stage) *Pipeline {
+	p.stages = append(p.stages, s)
+	return p
+}
+
+// Gzip compresses the data with the default compression level
+func (p *Pipeline) Gzip() *Pipeline {
+	return p.add(func(r *run, src io.Reader) (io.Reader, error) {
+		return r.pipe(func(w io.Writer) error {
+			gz := gzip.NewWriter(w)
+			if _, err := ioutil.Copy(r.ctx, gz, src); err != nil {
+				return err
+			}
+			return gz.Close()
+		}), nil
+	})
+}
+
+// Gunzip decompresses gzip compressed data
+func (p *Pipeline) Gunzip() *Pipeline {
+	return p.add(func(r *run, src io.Reader) (io.Reader, error) {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return nil, err
+		}
+		r.closers = append(r.closers, gz)
+		return gz, nil
+	})
+}
+
+// Hash computes the digest of the data at this point of the pipeline, reported in Result.Digests
+func (p *Pipeline) Hash(alg digest.Algorithm) *Pipeline {
+	return p.add(func(r *run, src io.Reader) (io.Reader, error) {
+		digester, err := digest.NewDigester(alg)
+		if err != nil {
+			return nil, err
+		}
+		i := len(r.result.Digests)
+		r.result.Digests = append(r.result.Digests, digest.Digest{})
+		r.finish = append(r.finish, func() error {
+			r.result.Digests[i] = digester.Digest()
+			return nil
+		})
+		return io.TeeReader(src, digester), nil
+	})
+}
+
+// Verify fails the run with an error wrapping digest.ErrMismatch if the digest of the data at this
+// point of the pipeline differs from expected. The sink has received the data by then, so callers
+// must discard it on error.
+func (p *Pipeline) Verify(expected digest.Digest) *Pipeline {
+	return p.add(func(r *run, src io.Reader) (io.Reader, error) {
+		digester, err := digest.NewDigester(expected.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		r.finish = append(r.finish, func() error {
+			if actual := digester.Digest(); actual != expected {
+				return fmt.Errorf("%w: expected %s, got %s", digest.ErrMismatch, expected, actual)
+			}
+			return nil
+		})
+		return io.TeeReader(src, digester), nil
+	})
+}
+
+// RateLimit limits the throughput to bytesPerSecond
+func (p *Pipeline) RateLimit(bytesPerSecond int) *Pipeline {
+	return p.add(func(r *run, src io.Reader) (io.Reader, error) {
+		return ioutil.NewRateLimitedReader(r.ctx, src, bytesPerSecond), nil
+	})
+}
+
+// Progress reports the bytes passing this point of the pipeline to reporter, with total being the
+// expected number of bytes or 0 if unknown
+func (p *Pipeline) Progress(reporter progress.Reporter, operation, path string, total int64) *Pipeline {
+	return p.add(func(r *run, src io.Reader) (io.Reader, error) {
+		tracker := progress.NewTracker(reporter, operation, path, total)
+		r.finish = append(r.finish, func() error {
+			tracker.Finish()
+			return nil
+		})
+		return tracker.Reader(src), nil
+	})
+}
+
+// Transform adds a custom stage, e.g. for encryption. fn is called when the pipeline runs.
+func (p *Pipeline) Transform(fn func(ctx context.Context, src io.Reader) (io.Reader, error)) *Pipeline {
+	return p.add(func(r *run, src io.Reader) (io.Reader, error) {
+		return fn(r.ctx, src)
+	})
+}
+
+// To runs the pipeline, writing to sink until the source is exhausted, a stage fails, or the
+// context is done
+func (p *Pipeline) To(ctx context.Context, sink io.Writer) (*Result, error) {
+	r := &run{ctx: ctx}
+	defer func() {
+		for _, c := range r.closers {
+			_ = c.Close()
+		}
+	}()
+	src := p.src
+	for _, s := range p.stages {
+		var err error
+		if src, err = s(r, src); err != nil {
+			return nil, err
+		}
+	}
+	n, err := ioutil.Copy(ctx, sink, src)
+	r.result.Bytes = n
+	if err != nil {
+		return nil, err
+	}
+	for _, finish := range r.finish {
+		if err := finish(); err != nil {
+			return nil, err
+		}
+	}
+	return &r.result, nil
+}
+
+// ToFile runs the pipeline, writing to the file at path. The file is removed if the run fails.
+func (p *Pipeline) ToFile(ctx context.Context, path string) (*Result, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.To(ctx, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return result, nil
+}
+
+// pipe runs fn in a goroutine and returns a reader for what it writes. The goroutine is stopped
+// when the run ends.
+func (r *run) pipe(fn func(w io.Writer) error) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(fn(pw))
+	}()
+	r.closers = append(r.closers, pr)
+	return pr
+}
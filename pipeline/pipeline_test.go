@@ -0,0 +1,82 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/argoproj/pkg/v2/digest"
+	"github.com/argoproj/pkg/v2/progress"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	data := strings.Repeat("hello world ", 10_000)
+	expected, err := digest.FromBytes(digest.SHA256, []byte(data))
+	require.NoError(t, err)
+
+	var compressed bytes.Buffer
+	result, err := New(strings.NewReader(data)).Hash(digest.SHA256).Gzip().Hash(digest.CRC32C).To(ctx, &compressed)
+	require.NoError(t, err)
+	assert.Equal(t, int64(compressed.Len()), result.Bytes)
+	assert.Less(t, result.Bytes, int64(len(data)))
+	require.Len(t, result.Digests, 2)
+	assert.Equal(t, expected, result.Digests[0])
+	crc, err := digest.FromBytes(digest.CRC32C, compressed.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, crc, result.Digests[1])
+
+	path := filepath.Join(t.TempDir(), "restored")
+	var events []progress.Event
+	reporter := progress.ReporterFunc(func(e progress.Event) { events = append(events, e) })
+	result, err = New(bytes.NewReader(compressed.Bytes())).
+		Gunzip().
+		Verify(expected).
+		RateLimit(100<<20).
+		Progress(reporter, "download", "my-key", int64(len(data))).
+		ToFile(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), result.Bytes)
+	restored, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, string(restored))
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, int64(len(data)), last.BytesDone)
+	assert.Equal(t, "my-key", last.Path)
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "file")
+	other, err := digest.FromBytes(digest.SHA256, []byte("other"))
+	require.NoError(t, err)
+	_, err = New(strings.NewReader("hello")).Verify(other).ToFile(ctx, path)
+	require.ErrorIs(t, err, digest.ErrMismatch)
+	assert.NoFileExists(t, path)
+
+	_, err = New(strings.NewReader("not gzip")).Gunzip().To(ctx, io.Discard)
+	require.Error(t, err)
+
+	_, err = New(strings.NewReader("hello")).Hash("md4").To(ctx, io.Discard)
+	require.EqualError(t, err, "unsupported digest algorithm 'md4'")
+
+	_, err = New(strings.NewReader("hello")).Transform(func(context.Context, io.Reader) (io.Reader, error) {
+		return nil, errors.New("no key")
+	}).To(ctx, io.Discard)
+	require.EqualError(t, err, "no key")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = New(strings.NewReader("hello")).Gzip().To(cancelled, io.Discard)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,319 @@
+package proc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrNotSupported is returned on platforms without a /proc filesystem
+var ErrNotSupported = errors.New("process table inspection is not supported on this platform")
+
+// userHZ is the unit of the time fields in /proc/<pid>/stat. It is fixed at 100 for all
+// architectures supported by Go.
+const userHZ = 100
+
+// Process describes a process
+type Process struct {
+	PID  int
+	PPID int
+	// Comm is the executable name, truncated by the kernel to 15 characters
+	Comm string
+	// Cmdline is the full command line. It is empty for kernel threads and zombies.
+	Cmdline []string
+	// State is the single character process state, e.g. R (running), S (sleeping) or Z (zombie)
+	State     string
+	StartTime time.Time
+}
+
+// ResourceUsage is the resource usage of a process
+type ResourceUsage struct {
+	// RSS is the resident set size in bytes. It is zero for zombies.
+	RSS uint64
+	// UserTime and SystemTime are the CPU time spent in user and kernel mode
+	UserTime   time.Duration
+	SystemTime time.Duration
+}
+
+// CPUTime returns the total CPU time
+func (u ResourceUsage) CPUTime() time.Duration {
+	return u.UserTime + u.SystemTime
+}
+
+// Table provides access to the process table. It exists so that code supervising processes can be
+// tested against a fake process table.
+type Table interface {
+	// List returns the PIDs of all processes in ascending order
+	List() ([]int, error)
+	// Info returns details about the process
+	Info(pid int) (*Process, error)
+	// Children returns the PIDs of the direct children of the process in ascending order
+	Children(pid int) ([]int, error)
+	// Usage returns the resource usage of the process
+	Usage(pid int) (ResourceUsage, error)
+}
+
+// NewTable returns a Table backed by the /proc filesystem mounted at root. Use "/proc" for the
+// host, or a fake directory tree in tests.
+func NewTable(root string) Table {
+	return &procTable{root: root}
+}
+
+// List returns the PIDs of all processes using the default table
+func List() ([]int, error) {
+	return defaultTable.List()
+}
+
+// Info returns details about the process using the default table
+func Info(pid int) (*Process, error) {
+	return defaultTable.Info(pid)
+}
+
+// Children returns the PIDs of the direct children of the process using the default table
+func Children(pid int) ([]int, error) {
+	return defaultTable.Children(pid)
+}
+
+// Usage returns the resource usage of the process using the default table
+func Usage(pid int) (ResourceUsage, error) {
+	return defaultTable.Usage(pid)
+}
+
+// SignalTree sends the signal to the process and all of its descendants using the default table
+func SignalTree(pid int, sig syscall.Signal) error {
+	return signalTree(defaultTable, pid, sig, kill)
+}
+
+// signalTree signals the process before its descendants, so that a process which is being
+// terminated is not left running with its children gone. The descendants are listed before
+// signalling anything, so they can still be found after being re-parented. Descendants which exit
+// in the meantime are not an error.
+func signalTree(t Table, pid int, sig syscall.Signal, kill func(int, syscall.Signal) error) error {
+	pids, err := descendants(t, pid)
+	if err != nil {
+		return err
+	}
+	if err := kill(pid, sig); err != nil {
+		return fmt.Errorf("failed to signal process %d: %w", pid, err)
+	}
+	var errs []error
+	for _, p := range pids {
+		if err := kill(p, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			errs = append(errs, fmt.Errorf("failed to signal process %d: %w", p, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// descendants returns the PIDs of all descendants of the process, parents before their children
+func descendants(t Table, pid int) ([]int, error) {
+	pids, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+	// the parent is all that is needed, so the procfs table skips the cmdline and boot time
+	parent := func(p int) (int, error) {
+		info, err := t.Info(p)
+		if err != nil {
+			return 0, err
+		}
+		return info.PPID, nil
+	}
+	if pt, ok := t.(*procTable); ok {
+		parent = pt.parent
+	}
+	children := map[int][]int{}
+	for _, p := range pids {
+		ppid, err := parent(p)
+		if err != nil {
+			// the process exited since it was listed
+			continue
+		}
+		children[ppid] = append(children[ppid], p)
+	}
+	var result []int
+	queue := children[pid]
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		result = append(result, p)
+		queue = append(queue, children[p]...)
+	}
+	return result, nil
+}
+
+type procTable struct {
+	root string
+}
+
+func (t *procTable) List() ([]int, error) {
+	entries, err := os.ReadDir(t.root)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+	return pids, nil
+}
+
+func (t *procTable) Info(pid int) (*Process, error) {
+	stat, err := os.ReadFile(filepath.Join(t.root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	info, fields, err := parseStat(stat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stat of process %d: %w", pid, err)
+	}
+	startTicks, err := strconv.ParseUint(fields[statStartTime], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stat of process %d: %w", pid, err)
+	}
+	bootTime, err := t.bootTime()
+	if err != nil {
+		return nil, err
+	}
+	info.StartTime = bootTime.Add(time.Duration(startTicks) * time.Second / userHZ)
+
+	cmdline, err := os.ReadFile(filepath.Join(t.root, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	cmdline = bytes.TrimRight(cmdline, "\x00")
+	if len(cmdline) > 0 {
+		info.Cmdline = strings.Split(string(cmdline), "\x00")
+	}
+	return info, nil
+}
+
+func (t *procTable) Children(pid int) ([]int, error) {
+	pids, err := t.List()
+	if err != nil {
+		return nil, err
+	}
+	var children []int
+	for _, p := range pids {
+		// the process may have exited since it was listed
+		if ppid, err := t.parent(p); err == nil && ppid == pid {
+			children = append(children, p)
+		}
+	}
+	return children, nil
+}
+
+// parent returns the PID of the parent of the process, reading only its stat
+func (t *procTable) parent(pid int) (int, error) {
+	stat, err := os.ReadFile(filepath.Join(t.root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	info, _, err := parseStat(stat)
+	if err != nil {
+		return 0, err
+	}
+	return info.PPID, nil
+}
+
+func (t *procTable) Usage(pid int) (ResourceUsage, error) {
+	stat, err := os.ReadFile(filepath.Join(t.root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	_, fields, err := parseStat(stat)
+	if err != nil {
+		return ResourceUsage{}, fmt.Errorf("failed to parse stat of process %d: %w", pid, err)
+	}
+	var usage ResourceUsage
+	for field, d := range map[int]*time.Duration{statUserTime: &usage.UserTime, statSystemTime: &usage.SystemTime} {
+		ticks, err := strconv.ParseUint(fields[field], 10, 64)
+		if err != nil {
+			return ResourceUsage{}, fmt.Errorf("failed to parse stat of process %d: %w", pid, err)
+		}
+		*d = time.Duration(ticks) * time.Second / userHZ
+	}
+
+	// the RSS in stat is in pages, status reports it in kB without the need to know the page size
+	status, err := os.ReadFile(filepath.Join(t.root, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return ResourceUsage{}, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if value, ok := strings.CutPrefix(line, "VmRSS:"); ok {
+			kb, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 10, 64)
+			if err != nil {
+				return ResourceUsage{}, fmt.Errorf("failed to parse status of process %d: %w", pid, err)
+			}
+			usage.RSS = kb * 1024
+		}
+	}
+	return usage, nil
+}
+
+func (t *procTable) bootTime() (time.Time, error) {
+	stat, err := os.ReadFile(filepath.Join(t.root, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(stat), "\n") {
+		if value, ok := strings.CutPrefix(line, "btime "); ok {
+			btime, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(btime, 0), nil
+		}
+	}
+	return time.Time{}, errors.New("btime not found in stat")
+}
+
+// indexes of fields in /proc/<pid>/stat as returned by parseStat, which starts at the state in
+// field 3
+const (
+	statState      = 0
+	statPPID       = 1
+	statUserTime   = 11
+	statSystemTime = 12
+	statStartTime  = 19
+)
+
+// parseStat parses the contents of /proc/<pid>/stat and returns the fields following the command
+// name. The command name is enclosed in parentheses and may itself contain spaces and parentheses,
+// so the remaining fields are located from the last closing parenthesis.
+func parseStat(stat []byte) (*Process, []string, error) {
+	s := string(stat)
+	open := strings.IndexByte(s, '(')
+	closing := strings.LastIndexByte(s, ')')
+	if open < 0 || closing < open {
+		return nil, nil, errors.New("malformed stat")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(s[:open]))
+	if err != nil {
+		return nil, nil, err
+	}
+	fields := strings.Fields(s[closing+1:])
+	if len(fields) <= statStartTime {
+		return nil, nil, fmt.Errorf("expected at least 22 fields, got %d", len(fields)+2)
+	}
+	ppid, err := strconv.Atoi(fields[statPPID])
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Process{
+		PID:   pid,
+		PPID:  ppid,
+		Comm:  s[open+1 : closing],
+		State: fields[statState],
+	}, fields, nil
+}
@@ -0,0 +1,7 @@
+package proc
+
+import "syscall"
+
+var defaultTable = NewTable("/proc")
+
+var kill = syscall.Kill
@@ -0,0 +1,160 @@
+package proc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFakeProcess(t *testing.T, root string, pid, ppid int, comm, state string, startTicks int, cmdline ...string) {
+	dir := filepath.Join(root, fmt.Sprint(pid))
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	// fields 3 to 22 of /proc/<pid>/stat, with the start time last
+	fields := []string{state, fmt.Sprint(ppid)}
+	for i := 0; i < 17; i++ {
+		fields = append(fields, "0")
+	}
+	// user and system time
+	fields[statUserTime], fields[statSystemTime] = "250", "50"
+	fields = append(fields, fmt.Sprint(startTicks), "0")
+	stat := fmt.Sprintf("%d (%s) %s\n", pid, comm, strings.Join(fields, " "))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cmdline"), []byte(strings.Join(cmdline, "\x00")+"\x00"), 0o644))
+	status := "Name:\t" + comm + "\nState:\t" + state + "\n"
+	if state != "Z" {
+		status += "VmRSS:\t    2048 kB\n"
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "status"), []byte(status), 0o644))
+}
+
+func newFakeTable(t *testing.T) Table {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "stat"), []byte("cpu  1 2 3 4\nbtime 1700000000\nprocesses 10\n"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(root, "self"), 0o755))
+	writeFakeProcess(t, root, 1, 0, "argoexec", "S", 100, "/var/run/argo/argoexec", "emissary")
+	writeFakeProcess(t, root, 12, 1, "sh", "S", 250, "sh", "-c", "sleep 10")
+	writeFakeProcess(t, root, 13, 12, "my (weird) cmd", "Z", 300)
+	writeFakeProcess(t, root, 7, 1, "sidecar", "R", 150, "sidecar")
+	writeFakeProcess(t, root, 14, 12, "sleep", "S", 310, "sleep", "10")
+	return NewTable(root)
+}
+
+func TestFakeTable(t *testing.T) {
+	table := newFakeTable(t)
+	pids, err := table.List()
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 7, 12, 13, 14}, pids)
+
+	children, err := table.Children(1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{7, 12}, children)
+
+	p, err := table.Info(12)
+	require.NoError(t, err)
+	assert.Equal(t, &Process{
+		PID:       12,
+		PPID:      1,
+		Comm:      "sh",
+		Cmdline:   []string{"sh", "-c", "sleep 10"},
+		State:     "S",
+		StartTime: time.Unix(1700000002, int64(500*time.Millisecond)),
+	}, p)
+
+	p, err = table.Info(13)
+	require.NoError(t, err)
+	assert.Equal(t, "my (weird) cmd", p.Comm)
+	assert.Equal(t, "Z", p.State)
+	assert.Empty(t, p.Cmdline)
+
+	_, err = table.Info(99)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	usage, err := table.Usage(12)
+	require.NoError(t, err)
+	assert.Equal(t, ResourceUsage{RSS: 2 << 20, UserTime: 2500 * time.Millisecond, SystemTime: 500 * time.Millisecond}, usage)
+	assert.Equal(t, 3*time.Second, usage.CPUTime())
+	usage, err = table.Usage(13)
+	require.NoError(t, err)
+	assert.Zero(t, usage.RSS)
+}
+
+func TestSignalTree(t *testing.T) {
+	table := newFakeTable(t)
+	var signalled []int
+	kill := func(pid int, sig syscall.Signal) error {
+		assert.Equal(t, syscall.SIGTERM, sig)
+		signalled = append(signalled, pid)
+		if pid == 13 {
+			return syscall.ESRCH
+		}
+		return nil
+	}
+	require.NoError(t, signalTree(table, 12, syscall.SIGTERM, kill))
+	assert.Equal(t, []int{12, 13, 14}, signalled)
+
+	signalled = nil
+	require.NoError(t, signalTree(table, 7, syscall.SIGTERM, kill))
+	assert.Equal(t, []int{7}, signalled)
+
+	err := signalTree(table, 13, syscall.SIGTERM, kill)
+	require.ErrorIs(t, err, syscall.ESRCH)
+
+	// only the stat of each process is read to find the descendants
+	require.NoError(t, os.Remove(filepath.Join(table.(*procTable).root, "14", "cmdline")))
+	pids, err := descendants(table, 12)
+	require.NoError(t, err)
+	assert.Equal(t, []int{13, 14}, pids)
+}
+
+func TestParseStatErrors(t *testing.T) {
+	_, _, err := parseStat([]byte("garbage"))
+	require.Error(t, err)
+	_, _, err = parseStat([]byte("1 (init) S 0"))
+	require.Error(t, err)
+}
+
+func TestLiveProcessTable(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		_, err := List()
+		require.ErrorIs(t, err, ErrNotSupported)
+		return
+	}
+	cmd := exec.Command("sleep", "10")
+	require.NoError(t, cmd.Start())
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	children, err := Children(os.Getpid())
+	require.NoError(t, err)
+	assert.Contains(t, children, cmd.Process.Pid)
+
+	p, err := Info(cmd.Process.Pid)
+	require.NoError(t, err)
+	assert.Equal(t, os.Getpid(), p.PPID)
+	assert.Equal(t, []string{"sleep", "10"}, p.Cmdline)
+	assert.WithinDuration(t, time.Now(), p.StartTime, time.Minute)
+
+	pids, err := List()
+	require.NoError(t, err)
+	assert.Contains(t, pids, os.Getpid())
+
+	usage, err := Usage(os.Getpid())
+	require.NoError(t, err)
+	assert.NotZero(t, usage.RSS)
+
+	require.NoError(t, SignalTree(cmd.Process.Pid, syscall.SIGKILL))
+	err = cmd.Wait()
+	require.Error(t, err)
+	assert.Equal(t, syscall.SIGKILL, cmd.ProcessState.Sys().(syscall.WaitStatus).Signal())
+}
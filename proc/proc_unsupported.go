@@ -0,0 +1,29 @@
+//go:build !linux
+
+package proc
+
+import "syscall"
+
+var defaultTable Table = unsupportedTable{}
+
+func kill(int, syscall.Signal) error {
+	return ErrNotSupported
+}
+
+type unsupportedTable struct{}
+
+func (unsupportedTable) List() ([]int, error) {
+	return nil, ErrNotSupported
+}
+
+func (unsupportedTable) Info(int) (*Process, error) {
+	return nil, ErrNotSupported
+}
+
+func (unsupportedTable) Children(int) ([]int, error) {
+	return nil, ErrNotSupported
+}
+
+func (unsupportedTable) Usage(int) (ResourceUsage, error) {
+	return ResourceUsage{}, ErrNotSupported
+}
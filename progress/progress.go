@@ -0,0 +1,192 @@
+package progress
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// DefaultInterval is the minimum time between two events of a Tracker
+const DefaultInterval = time.Second
+
+// Event reports the progress of a transfer
+type Event struct {
+	// Operation is what is being done, e.g. "upload", "download", "copy" or "tar"
+	Operation string `json:"operation"`
+	// Path is the file path or object key
+	Path string `json:"path"`
+	// BytesDone is the number of bytes transferred so far
+	BytesDone int64 `json:"bytesDone"`
+	// BytesTotal is the total size of the transfer, or 0 if unknown
+	BytesTotal int64 `json:"bytesTotal,omitempty"`
+	// Rate is the average number of bytes transferred per second
+	Rate float64 `json:"rate"`
+	// ETA is the estimated remaining time, or 0 if unknown
+	ETA time.Duration `json:"eta,omitempty"`
+	// Done is set on the last event of a transfer
+	Done bool `json:"done,omitempty"`
+}
+
+// Percent returns the completed percentage, or -1 if the total is unknown
+func (e Event) Percent() float64 {
+	if e.BytesTotal <= 0 {
+		return -1
+	}
+	return float64(e.BytesDone) * 100 / float64(e.BytesTotal)
+}
+
+// Reporter receives progress events. Report is called synchronously by the transfer and should
+// return quickly.
+type Reporter interface {
+	Report(Event)
+}
+
+// ReporterFunc adapts a function to a Reporter
+type ReporterFunc func(Event)
+
+func (f ReporterFunc) Report(e Event) {
+	f(e)
+}
+
+// NewLogReporter returns a Reporter which logs events at info level
+func NewLogReporter(ctx context.Context) Reporter {
+	return ReporterFunc(func(e Event) {
+		logger := logging.FromContext(ctx).WithFields(logging.Fields{
+			"operation": e.Operation,
+			"path":      e.Path,
+			"bytes":     e.BytesDone,
+		})
+		if e.BytesTotal > 0 {
+			logger = logger.WithField("total", e.BytesTotal)
+		}
+		switch {
+		case e.Done:
+			logger.Infof("Completed %s of %s", e.Operation, e.Path)
+		case e.BytesTotal > 0:
+			logger.Infof("%s of %s at %.0f%%, %s remaining", e.Operation, e.Path, e.Percent(), e.ETA.Round(time.Second))
+		default:
+			logger.Infof("%s of %s in progress", e.Operation, e.Path)
+		}
+	})
+}
+
+// Tracker turns byte counts of a transfer into events, at most one per interval plus a final one.
+// It is safe for concurrent use.
+type Tracker struct {
+	reporter  Reporter
+	operation string
+	path      string
+	total     int64
+	interval  time.Duration
+	now       func() time.Time
+
+	lock       sync.Mutex
+	start      time.Time
+	lastReport time.Time
+	done       int64
+	finished   bool
+}
+
+// NewTracker returns a Tracker for a transfer of total bytes, or an unknown size if total is 0. A
+// nil reporter discards all events.
+func NewTracker(reporter Reporter, operation, path string, total int64) *Tracker {
+	now := time.Now()
+	return &Tracker{
+		reporter:  reporter,
+		operation: operation,
+		path:      path,
+		total:     total,
+		interval:  DefaultInterval,
+		now:       time.Now,
+		start:     now,
+	}
+}
+
+// SetInterval changes the minimum time between two events
+func (t *Tracker) SetInterval(d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.interval = d
+}
+
+// Add records n transferred bytes
+func (t *Tracker) Add(n int64) {
+	if t.reporter == nil || n == 0 {
+		return
+	}
+	t.lock.Lock()
+	t.done += n
+	now := t.now()
+	if t.finished || now.Sub(t.lastReport) < t.interval {
+		t.lock.Unlock()
+		return
+	}
+	t.lastReport = now
+	e := t.event(now)
+	t.lock.Unlock()
+	t.reporter.Report(e)
+}
+
+// Finish reports the final event. Later calls have no effect.
+func (t *Tracker) Finish() {
+	if t.reporter == nil {
+		return
+	}
+	t.lock.Lock()
+	if t.finished {
+		t.lock.Unlock()
+		return
+	}
+	t.finished = true
+	e := t.event(t.now())
+	t.lock.Unlock()
+	e.Done = true
+	e.ETA = 0
+	t.reporter.Report(e)
+}
+
+func (t *Tracker) event(now time.Time) Event {
+	e := Event{Operation: t.operation, Path: t.path, BytesDone: t.done, BytesTotal: t.total}
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 {
+		e.Rate = float64(t.done) / elapsed
+	}
+	if t.total > t.done && e.Rate > 0 {
+		e.ETA = time.Duration(float64(t.total-t.done) / e.Rate * float64(time.Second))
+	}
+	return e
+}
+
+// Reader returns a reader which tracks the bytes read from r
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	return &reader{r: r, t: t}
+}
+
+// Writer returns a writer which tracks the bytes written to w
+func (t *Tracker) Writer(w io.Writer) io.Writer {
+	return &writer{w: w, t: t}
+}
+
+type reader struct {
+	r io.Reader
+	t *Tracker
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.t.Add(int64(n))
+	return n, err
+}
+
+type writer struct {
+	w io.Writer
+	t *Tracker
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.t.Add(int64(n))
+	return n, err
+}
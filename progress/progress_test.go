@@ -0,0 +1,67 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+func TestTracker(t *testing.T) {
+	var events []Event
+	tracker := NewTracker(ReporterFunc(func(e Event) { events = append(events, e) }), "upload", "my-key", 1000)
+	now := time.Unix(0, 0)
+	tracker.now = func() time.Time { return now }
+	tracker.start = now
+
+	now = now.Add(time.Second)
+	tracker.Add(100)
+	// throttled
+	tracker.Add(100)
+	now = now.Add(time.Second)
+	tracker.Add(200)
+	tracker.Finish()
+	tracker.Finish()
+
+	assert.Equal(t, []Event{
+		{Operation: "upload", Path: "my-key", BytesDone: 100, BytesTotal: 1000, Rate: 100, ETA: 9 * time.Second},
+		{Operation: "upload", Path: "my-key", BytesDone: 400, BytesTotal: 1000, Rate: 200, ETA: 3 * time.Second},
+		{Operation: "upload", Path: "my-key", BytesDone: 400, BytesTotal: 1000, Rate: 200, Done: true},
+	}, events)
+	assert.Equal(t, 40.0, events[2].Percent())
+	assert.Equal(t, -1.0, Event{}.Percent())
+}
+
+func TestReaderWriter(t *testing.T) {
+	var last Event
+	tracker := NewTracker(ReporterFunc(func(e Event) { last = e }), "copy", "file", 0)
+	tracker.SetInterval(0)
+	var buf bytes.Buffer
+	_, err := io.Copy(tracker.Writer(&buf), tracker.Reader(strings.NewReader("hello")))
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), last.BytesDone)
+	assert.Equal(t, time.Duration(0), last.ETA)
+
+	// a tracker without reporter does nothing
+	NewTracker(nil, "copy", "file", 0).Finish()
+}
+
+func TestLogReporter(t *testing.T) {
+	var buf bytes.Buffer
+	l := log.New()
+	l.SetOutput(&buf)
+	l.SetFormatter(&log.TextFormatter{DisableTimestamp: true})
+	reporter := NewLogReporter(logging.NewContext(context.Background(), logging.NewLogrus(l, nil)))
+	reporter.Report(Event{Operation: "download", Path: "my-key", BytesDone: 50, BytesTotal: 100, ETA: 2 * time.Second})
+	reporter.Report(Event{Operation: "download", Path: "my-key", BytesDone: 100, Done: true})
+	assert.Equal(t, "level=info msg=\"download of my-key at 50%, 2s remaining\" bytes=50 operation=download path=my-key total=100\n"+
+		"level=info msg=\"Completed download of my-key\" bytes=100 operation=download path=my-key\n", buf.String())
+}
@@ -0,0 +1,45 @@
+package reaper
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotSupported is returned on platforms where zombie processes cannot be reaped
+var ErrNotSupported = errors.New("reaping is not supported on this platform")
+
+// Status is the exit status of a reaped process
+type Status struct {
+	PID int
+	// ExitCode is the exit code of the process, or -1 if it was killed by a signal
+	ExitCode int
+	// Signal is the signal that killed the process, or 0 if it exited normally
+	Signal int
+}
+
+// Options configures the reaper
+type Options struct {
+	// OnReap is called from the reaper goroutine for every reaped process. It must not block.
+	OnReap func(Status)
+	// Subreaper marks the process as a child subreaper if it is not PID 1, so that orphaned
+	// descendants are re-parented to it instead of to the init process of the namespace. This
+	// allows reaping in wrappers that do not run as PID 1, e.g. when the pod shares its process
+	// namespace.
+	Subreaper bool
+}
+
+// Start is StartWithOptions with the default options
+func Start(ctx context.Context) error {
+	return StartWithOptions(ctx, Options{})
+}
+
+// StartWithOptions starts reaping zombie children in the background until the context is
+// cancelled. Unless Options.Subreaper is set it does nothing when the process is not PID 1, as only
+// PID 1 inherits orphaned processes.
+//
+// The reaper waits for any child, so it competes with exec.Cmd.Wait for children started by this
+// process. Callers that start children themselves must be prepared for Wait to fail with ECHILD,
+// and should rely on OnReap for the exit status instead.
+func StartWithOptions(ctx context.Context, opts Options) error {
+	return start(ctx, opts)
+}
@@ -0,0 +1,73 @@
+package reaper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+func start(ctx context.Context, opts Options) error {
+	logger := logging.FromContext(ctx)
+	if os.Getpid() != 1 {
+		if !opts.Subreaper {
+			logger.Debugf("Not running as PID 1, zombie reaping disabled")
+			return nil
+		}
+		if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+			return fmt.Errorf("failed to become a child subreaper: %w", err)
+		}
+	}
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGCHLD)
+	go func() {
+		defer signal.Stop(sigCh)
+		// children may have exited before the signal handler was installed
+		reapAll(ctx, opts)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sigCh:
+				reapAll(ctx, opts)
+			}
+		}
+	}()
+	logger.Infof("Zombie reaper started")
+	return nil
+}
+
+// reapAll reaps exited children until none are left. SIGCHLD is not queued, so a single signal
+// may stand for many exited children.
+func reapAll(ctx context.Context, opts Options) {
+	for {
+		var ws syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, syscall.ECHILD) {
+				logging.FromContext(ctx).Warnf("failed to reap children: %v", err)
+			}
+			return
+		}
+		if pid <= 0 {
+			return
+		}
+		status := Status{PID: pid, ExitCode: ws.ExitStatus()}
+		if ws.Signaled() {
+			status.Signal = int(ws.Signal())
+		}
+		logging.FromContext(ctx).WithFields(logging.Fields{"pid": pid, "exitCode": status.ExitCode, "signal": status.Signal}).Debugf("Reaped process")
+		if opts.OnReap != nil {
+			opts.OnReap(status)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package reaper
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartNotPID1(t *testing.T) {
+	require.NoError(t, Start(context.Background()))
+}
+
+func TestSubreaper(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		require.ErrorIs(t, StartWithOptions(context.Background(), Options{Subreaper: true}), ErrNotSupported)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reaped := make(chan Status, 10)
+	require.NoError(t, StartWithOptions(ctx, Options{Subreaper: true, OnReap: func(s Status) { reaped <- s }}))
+
+	// the background shell is orphaned when the outer shell exits, and re-parented to this process
+	cmd := exec.Command("sh", "-c", "(sleep 0.2; exit 3) & exit 0")
+	require.NoError(t, cmd.Start())
+
+	statuses := map[int]Status{}
+	timeout := time.After(10 * time.Second)
+	for len(statuses) < 2 {
+		select {
+		case s := <-reaped:
+			statuses[s.PID] = s
+		case <-timeout:
+			t.Fatalf("timed out, reaped %v", statuses)
+		}
+	}
+	require.Contains(t, statuses, cmd.Process.Pid)
+	assert.Equal(t, 0, statuses[cmd.Process.Pid].ExitCode)
+	delete(statuses, cmd.Process.Pid)
+	for _, s := range statuses {
+		assert.Equal(t, 3, s.ExitCode)
+		assert.Equal(t, 0, s.Signal)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package reaper
+
+import (
+	"context"
+	"os"
+)
+
+func start(_ context.Context, opts Options) error {
+	if os.Getpid() != 1 && !opts.Subreaper {
+		return nil
+	}
+	return ErrNotSupported
+}
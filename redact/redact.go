@@ -0,0 +1,86 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultReplacement is substituted for every match when no replacement is configured
+const DefaultReplacement = "******"
+
+// DefaultMaxMatchLength bounds the length of regexp matches which are detected across writes
+const DefaultMaxMatchLength = 256
+
+// Options configures what is redacted
+type Options struct {
+	// Secrets are literal strings to mask
+	Secrets []string
+	// Patterns are regular expressions to mask
+	Patterns []*regexp.Regexp
+	// Replacement is written in place of every match. Defaults to DefaultReplacement.
+	Replacement string
+	// MaxMatchLength is the length of the longest pattern match which is guaranteed to be masked
+	// when it is split across several writes. Defaults to DefaultMaxMatchLength if patterns are
+	// configured. It is always at least the length of the longest secret.
+	MaxMatchLength int
+}
+
+// Redactor masks secrets and pattern matches
+type Redactor struct {
+	re          *regexp.Regexp
+	replacement []byte
+	maxLen      int
+}
+
+// New returns a Redactor for the given options
+func New(opts Options) *Redactor {
+	r := &Redactor{
+		replacement: []byte(opts.Replacement),
+		maxLen:      opts.MaxMatchLength,
+	}
+	if opts.Replacement == "" {
+		r.replacement = []byte(DefaultReplacement)
+	}
+	if r.maxLen <= 0 && len(opts.Patterns) > 0 {
+		r.maxLen = DefaultMaxMatchLength
+	}
+
+	// longer secrets come first, so that a secret which is a prefix of another does not leave the
+	// remainder of the longer one unmasked
+	secrets := make([]string, 0, len(opts.Secrets))
+	for _, s := range opts.Secrets {
+		if s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+
+	var alternatives []string
+	for _, s := range secrets {
+		alternatives = append(alternatives, regexp.QuoteMeta(s))
+		if len(s) > r.maxLen {
+			r.maxLen = len(s)
+		}
+	}
+	for _, p := range opts.Patterns {
+		alternatives = append(alternatives, "(?:"+p.String()+")")
+	}
+	if len(alternatives) > 0 {
+		r.re = regexp.MustCompile(strings.Join(alternatives, "|"))
+	}
+	return r
+}
+
+// Redact returns a copy of b with every match replaced
+func (r *Redactor) Redact(b []byte) []byte {
+	if r.re == nil {
+		return append([]byte{}, b...)
+	}
+	return r.re.ReplaceAllLiteral(b, r.replacement)
+}
+
+// RedactString returns a copy of s with every match replaced
+func (r *Redactor) RedactString(s string) string {
+	return string(r.Redact([]byte(s)))
+}
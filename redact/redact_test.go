@@ -0,0 +1,81 @@
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	r := New(Options{
+		Secrets:  []string{"hunter2", "hunter2-long"},
+		Patterns: []*regexp.Regexp{regexp.MustCompile(`token=\w+`)},
+	})
+	assert.Equal(t, "password ****** and ******", r.RedactString("password hunter2-long and hunter2"))
+	assert.Equal(t, "url?****** end", r.RedactString("url?token=abc123 end"))
+	assert.Equal(t, "nothing here", New(Options{}).RedactString("nothing here"))
+	assert.Equal(t, "[x]", New(Options{Secrets: []string{"s"}, Replacement: "[x]"}).RedactString("s"))
+}
+
+func TestWriterAcrossWrites(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, Options{Secrets: []string{"supersecret"}})
+	for _, chunk := range []string{"the password is sup", "er", "secret, and again supersecre", "t!"} {
+		n, err := w.Write([]byte(chunk))
+		require.NoError(t, err)
+		assert.Equal(t, len(chunk), n)
+		assert.NotContains(t, out.String(), "sup")
+	}
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "the password is ******, and again ******!", out.String())
+}
+
+func TestWriterByteByByte(t *testing.T) {
+	input := "a=token=abc123;b=xyz;token=qqq"
+	var out bytes.Buffer
+	w := NewWriter(&out, Options{
+		Secrets:        []string{"xyz"},
+		Patterns:       []*regexp.Regexp{regexp.MustCompile(`token=[a-z0-9]+`)},
+		MaxMatchLength: 16,
+	})
+	for i := 0; i < len(input); i++ {
+		_, err := w.Write([]byte{input[i]})
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	assert.Equal(t, "a=******;b=******;******", out.String())
+}
+
+func TestWriterStreamsData(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out, Options{Secrets: []string{"abc"}})
+	_, err := w.Write([]byte("0123456789"))
+	require.NoError(t, err)
+	// only the last len("abc")-1 bytes are held back
+	assert.Equal(t, "01234567", out.String())
+
+	out.Reset()
+	w = NewWriter(&out, Options{})
+	_, err = w.Write([]byte("0123456789"))
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", out.String())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestWriterError(t *testing.T) {
+	w := NewWriter(failingWriter{}, Options{Secrets: []string{"abc"}})
+	// the data is buffered even if the flushed part cannot be written
+	n, err := w.Write([]byte("0123456789"))
+	require.Error(t, err)
+	assert.Equal(t, 10, n)
+	require.Error(t, w.Flush())
+}
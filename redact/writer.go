@@ -0,0 +1,85 @@
+package redact
+
+import (
+	"io"
+	"sync"
+)
+
+// Writer masks secrets in the data written to it before passing it on to the underlying writer.
+// Matches which span several Write calls are masked too: up to MaxMatchLength-1 bytes are held back
+// until more data arrives, or Flush is called.
+type Writer struct {
+	w        io.Writer
+	redactor *Redactor
+
+	lock sync.Mutex
+	buf  []byte
+}
+
+// NewWriter returns a Writer which redacts data according to the options before writing it to w
+func NewWriter(w io.Writer, opts Options) *Writer {
+	return NewWriterWithRedactor(w, New(opts))
+}
+
+// NewWriterWithRedactor returns a Writer which uses an existing Redactor
+func NewWriterWithRedactor(w io.Writer, redactor *Redactor) *Writer {
+	return &Writer{w: w, redactor: redactor}
+}
+
+// Write buffers p and writes all data which can no longer be part of a match. p is always accepted
+// into the buffer, so len(p) is returned also when writing to the underlying writer fails.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.buf = append(w.buf, p...)
+	if w.redactor.re == nil {
+		return len(p), w.flush(len(w.buf))
+	}
+	return len(p), w.flush(w.boundary())
+}
+
+// Flush writes all buffered data. It should be called once no more data is expected.
+func (w *Writer) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.flush(len(w.buf))
+}
+
+// Close flushes the buffered data. The underlying writer is not closed.
+func (w *Writer) Close() error {
+	return w.Flush()
+}
+
+// boundary returns the length of the buffer prefix which can safely be redacted and written. The
+// remainder holds back enough data to recognize a match which is completed by a later write.
+func (w *Writer) boundary() int {
+	end := len(w.buf) - (w.redactor.maxLen - 1)
+	if end <= 0 {
+		return 0
+	}
+	// do not cut through a match: a match which spans the boundary is either written in full, if it
+	// cannot grow any further, or held back entirely
+	for _, loc := range w.redactor.re.FindAllIndex(w.buf, -1) {
+		if loc[0] >= end {
+			break
+		}
+		if loc[1] > end {
+			if loc[1] < len(w.buf) {
+				return loc[1]
+			}
+			return loc[0]
+		}
+	}
+	return end
+}
+
+// flush must be called with the lock held
+func (w *Writer) flush(n int) error {
+	if n == 0 {
+		return nil
+	}
+	out := w.redactor.Redact(w.buf[:n])
+	w.buf = append(w.buf[:0], w.buf[n:]...)
+	_, err := w.w.Write(out)
+	return err
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// CertReloader serves a TLS certificate loaded from files and reloads it when the files change
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	lock    sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader loads the key pair from the given files
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate can be used as tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cert, nil
+}
+
+// Reload re-reads the key pair from disk
+func (r *CertReloader) Reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair %s, %s: %w", r.certFile, r.keyFile, err)
+	}
+	r.lock.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.lock.Unlock()
+	return nil
+}
+
+// Watch periodically reloads the key pair if either file changed, until the context is cancelled.
+// Failures are logged and the previous certificate is kept.
+func (r *CertReloader) Watch(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, err := r.latestModTime()
+			if err != nil {
+				logging.FromContext(ctx).Warnf("could not stat certificate files: %v", err)
+				continue
+			}
+			r.lock.RLock()
+			changed := !modTime.Equal(r.modTime)
+			r.lock.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				logging.FromContext(ctx).Warnf("could not reload certificate: %v", err)
+				continue
+			}
+			logging.FromContext(ctx).WithField("cert", r.certFile).Infof("Reloaded TLS certificate")
+		}
+	}
+}
+
+// latestModTime returns the most recent modification time of the key pair. Stat follows symlinks,
+// which is how Kubernetes atomically swaps the contents of mounted secrets.
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultShutdownTimeout   = 30 * time.Second
+	DefaultCertReloadPeriod  = time.Minute
+)
+
+// Options configures a Server. Zero values are replaced by the defaults above.
+type Options struct {
+	// Addr is the address to listen on, e.g. ":8080"
+	Addr    string
+	Handler http.Handler
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	// WriteTimeout bounds the time to write a response. Handlers which take longer, e.g. to
+	// stream a profile or a support bundle, must extend or clear their deadline with
+	// http.ResponseController.SetWriteDeadline.
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// ShutdownTimeout bounds how long in-flight requests are given to finish on shutdown
+	ShutdownTimeout time.Duration
+	// DrainDelay is how long the server keeps accepting requests after shutdown was requested, while
+	// reporting itself as draining. This gives load balancers time to stop routing traffic to the pod.
+	DrainDelay time.Duration
+
+	// CertFile and KeyFile enable TLS. The files are re-read when they change, which makes rotation of
+	// certificates mounted from a Kubernetes secret transparent.
+	CertFile string
+	KeyFile  string
+	// CertReloadPeriod is how often the certificate files are checked for changes
+	CertReloadPeriod time.Duration
+	// TLSConfig is used as the base TLS configuration when TLS is enabled
+	TLSConfig *tls.Config
+
+	// H2C enables HTTP/2 over cleartext connections. Ignored when TLS is enabled.
+	H2C bool
+}
+
+// Server wraps http.Server with sane timeouts, graceful shutdown and optional TLS hot-reload
+type Server struct {
+	opts     Options
+	server   *http.Server
+	reloader *CertReloader
+	draining atomic.Bool
+}
+
+// New returns a new Server for the given options
+func New(opts Options) (*Server, error) {
+	if opts.Handler == nil {
+		opts.Handler = http.DefaultServeMux
+	}
+	opts.ReadHeaderTimeout = orDefault(opts.ReadHeaderTimeout, DefaultReadHeaderTimeout)
+	opts.ReadTimeout = orDefault(opts.ReadTimeout, DefaultReadTimeout)
+	opts.WriteTimeout = orDefault(opts.WriteTimeout, DefaultWriteTimeout)
+	opts.IdleTimeout = orDefault(opts.IdleTimeout, DefaultIdleTimeout)
+	opts.ShutdownTimeout = orDefault(opts.ShutdownTimeout, DefaultShutdownTimeout)
+	opts.CertReloadPeriod = orDefault(opts.CertReloadPeriod, DefaultCertReloadPeriod)
+
+	s := &Server{opts: opts}
+	handler := opts.Handler
+	if opts.H2C && !s.tlsEnabled() {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: opts.IdleTimeout})
+	}
+	s.server = &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+	}
+
+	if s.tlsEnabled() {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, errors.New("both CertFile and KeyFile must be set to enable TLS")
+		}
+		reloader, err := NewCertReloader(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.reloader = reloader
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if opts.TLSConfig != nil {
+			tlsConfig = opts.TLSConfig.Clone()
+		}
+		tlsConfig.GetCertificate = reloader.GetCertificate
+		s.server.TLSConfig = tlsConfig
+	}
+	return s, nil
+}
+
+func (s *Server) tlsEnabled() bool {
+	return s.opts.CertFile != "" || s.opts.KeyFile != ""
+}
+
+// Draining returns true once shutdown has been requested. Health handlers should use it to report
+// the server as not ready.
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// Run serves requests until the context is cancelled, then drains in-flight requests and returns.
+// A nil error is returned after a graceful shutdown.
+func (s *Server) Run(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, ln)
+}
+
+// RunUntilSignal is like Run but additionally shuts down upon SIGINT or SIGTERM
+func (s *Server) RunUntilSignal(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return s.Run(ctx)
+}
+
+// Serve serves requests on the given listener until the context is cancelled
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	if s.reloader != nil {
+		go s.reloader.Watch(ctx, s.opts.CertReloadPeriod)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if s.tlsEnabled() {
+			err = s.server.ServeTLS(ln, "", "")
+		} else {
+			err = s.server.Serve(ln)
+		}
+		errCh <- err
+	}()
+	logger := logging.FromContext(ctx)
+	logger.WithField("addr", ln.Addr().String()).Infof("HTTP server started")
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	s.draining.Store(true)
+	if s.opts.DrainDelay > 0 {
+		logger.Infof("HTTP server draining for %v", s.opts.DrainDelay)
+		time.Sleep(s.opts.DrainDelay)
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.opts.ShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		_ = s.server.Close()
+		return fmt.Errorf("HTTP server shutdown: %w", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Infof("HTTP server stopped")
+	return nil
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+)
+
+func writeKeyPair(t *testing.T, dir, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func startServer(t *testing.T, opts Options) (*Server, string, context.CancelFunc, chan error) {
+	s, err := New(opts)
+	require.NoError(t, err)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Serve(ctx, ln)
+	}()
+	return s, ln.Addr().String(), cancel, errCh
+}
+
+func TestNewDefaults(t *testing.T) {
+	s, err := New(Options{Addr: ":0"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultReadHeaderTimeout, s.server.ReadHeaderTimeout)
+	assert.Equal(t, DefaultWriteTimeout, s.server.WriteTimeout)
+	assert.Equal(t, DefaultShutdownTimeout, s.opts.ShutdownTimeout)
+
+	_, err = New(Options{CertFile: "tls.crt"})
+	require.Error(t, err)
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	})
+	s, addr, cancel, errCh := startServer(t, Options{Handler: handler})
+
+	respCh := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			respCh <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		respCh <- string(body)
+	}()
+
+	<-started
+	cancel()
+	assert.Equal(t, "done", <-respCh)
+	require.NoError(t, <-errCh)
+	assert.True(t, s.Draining())
+}
+
+func TestH2C(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+	_, addr, cancel, errCh := startServer(t, Options{Handler: handler, H2C: true})
+	defer func() {
+		cancel()
+		require.NoError(t, <-errCh)
+	}()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + addr)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", string(body))
+}
+
+func TestTLSReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "first")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	_, addr, cancel, errCh := startServer(t, Options{Handler: handler, CertFile: certFile, KeyFile: keyFile, CertReloadPeriod: 10 * time.Millisecond})
+	defer func() {
+		cancel()
+		require.NoError(t, <-errCh)
+	}()
+
+	peerName := func() string {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true}) //nolint:gosec
+		require.NoError(t, err)
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "first", peerName())
+
+	// ensure the modification time changes on filesystems with coarse timestamps
+	time.Sleep(10 * time.Millisecond)
+	writeKeyPair(t, dir, "second")
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	assert.Eventually(t, func() bool { return peerName() == "second" }, 5*time.Second, 20*time.Millisecond)
+}
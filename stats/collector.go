@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	heapAllocDesc    = prometheus.NewDesc("runtime_heap_alloc_bytes", "Size of live heap objects.", nil, nil)
+	heapSysDesc      = prometheus.NewDesc("runtime_heap_sys_bytes", "Heap memory obtained from the OS.", nil, nil)
+	sysDesc          = prometheus.NewDesc("runtime_sys_bytes", "Memory obtained from the OS.", nil, nil)
+	totalAllocDesc   = prometheus.NewDesc("runtime_alloc_bytes_total", "Cumulative bytes allocated for heap objects.", nil, nil)
+	gcDesc           = prometheus.NewDesc("runtime_gc_total", "Number of completed GC cycles.", nil, nil)
+	lastGCPauseDesc  = prometheus.NewDesc("runtime_gc_last_pause_seconds", "Stop-the-world pause of the last GC.", nil, nil)
+	gcPauseTotalDesc = prometheus.NewDesc("runtime_gc_pause_seconds_total", "Cumulative stop-the-world pause of all GCs.", nil, nil)
+	goroutinesDesc   = prometheus.NewDesc("runtime_goroutines", "Number of goroutines.", nil, nil)
+	openFDsDesc      = prometheus.NewDesc("runtime_open_fds", "Number of open file descriptors.", nil, nil)
+)
+
+// Collector returns a Prometheus collector exporting a snapshot of the runtime statistics on every
+// scrape. The metric names are prefixed with runtime_ so that they do not collide with those of the
+// standard Go collector. The open file descriptors are omitted where they cannot be counted.
+func Collector() prometheus.Collector {
+	return snapshotCollector{}
+}
+
+type snapshotCollector struct{}
+
+func (snapshotCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{heapAllocDesc, heapSysDesc, sysDesc, totalAllocDesc, gcDesc, lastGCPauseDesc, gcPauseTotalDesc, goroutinesDesc, openFDsDesc} {
+		ch <- desc
+	}
+}
+
+func (snapshotCollector) Collect(ch chan<- prometheus.Metric) {
+	s := ReadSnapshot()
+	ch <- prometheus.MustNewConstMetric(heapAllocDesc, prometheus.GaugeValue, float64(s.HeapAlloc))
+	ch <- prometheus.MustNewConstMetric(heapSysDesc, prometheus.GaugeValue, float64(s.HeapSys))
+	ch <- prometheus.MustNewConstMetric(sysDesc, prometheus.GaugeValue, float64(s.Sys))
+	ch <- prometheus.MustNewConstMetric(totalAllocDesc, prometheus.CounterValue, float64(s.TotalAlloc))
+	ch <- prometheus.MustNewConstMetric(gcDesc, prometheus.CounterValue, float64(s.NumGC))
+	ch <- prometheus.MustNewConstMetric(lastGCPauseDesc, prometheus.GaugeValue, s.LastGCPause.Seconds())
+	ch <- prometheus.MustNewConstMetric(gcPauseTotalDesc, prometheus.CounterValue, s.GCPauseTotal.Seconds())
+	ch <- prometheus.MustNewConstMetric(goroutinesDesc, prometheus.GaugeValue, float64(s.Goroutines))
+	if s.OpenFDs >= 0 {
+		ch <- prometheus.MustNewConstMetric(openFDsDesc, prometheus.GaugeValue, float64(s.OpenFDs))
+	}
+}
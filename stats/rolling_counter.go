@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// RollingCounter counts events over a sliding time window, e.g. to track error rates. Events are
+// kept in a ring of buckets of equal duration, so counts are accurate to one bucket.
+type RollingCounter struct {
+	resolution time.Duration
+	now        func() time.Time
+
+	lock    sync.Mutex
+	counts  []int64
+	periods []int64
+}
+
+// NewRollingCounter returns a counter keeping events for span, in buckets of the given resolution.
+// For example NewRollingCounter(15*time.Minute, 5*time.Second) can report the events of the last
+// 1, 5 and 15 minutes.
+func NewRollingCounter(span, resolution time.Duration) *RollingCounter {
+	buckets := int((span + resolution - 1) / resolution)
+	return &RollingCounter{
+		resolution: resolution,
+		now:        time.Now,
+		counts:     make([]int64, buckets),
+		periods:    make([]int64, buckets),
+	}
+}
+
+// Inc counts a single event
+func (c *RollingCounter) Inc() {
+	c.Add(1)
+}
+
+// Add counts n events
+func (c *RollingCounter) Add(n int64) {
+	period := c.period()
+	i := c.index(period)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.periods[i] != period {
+		c.periods[i] = period
+		c.counts[i] = 0
+	}
+	c.counts[i] += n
+}
+
+// Count returns the number of events in the last window, which is capped at the span of the
+// counter
+func (c *RollingCounter) Count(window time.Duration) int64 {
+	period := c.period()
+	buckets := min(int((window+c.resolution-1)/c.resolution), len(c.counts))
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var count int64
+	for p := period - int64(buckets) + 1; p <= period; p++ {
+		if i := c.index(p); c.periods[i] == p {
+			count += c.counts[i]
+		}
+	}
+	return count
+}
+
+// Rate returns the average number of events per second in the last window
+func (c *RollingCounter) Rate(window time.Duration) float64 {
+	if window <= 0 {
+		return 0
+	}
+	span := time.Duration(len(c.counts)) * c.resolution
+	return float64(c.Count(window)) / min(window, span).Seconds()
+}
+
+func (c *RollingCounter) period() int64 {
+	return c.now().UnixNano() / int64(c.resolution)
+}
+
+func (c *RollingCounter) index(period int64) int {
+	return int(period % int64(len(c.counts)))
+}
@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// Snapshot holds runtime statistics of the process
+type Snapshot struct {
+	Time time.Time
+	// HeapAlloc is the size of live heap objects, HeapSys the heap memory obtained from the OS and
+	// Sys all memory obtained from the OS
+	HeapAlloc  uint64
+	HeapSys    uint64
+	Sys        uint64
+	TotalAlloc uint64
+	NumGC      uint32
+	// LastGCPause is the stop-the-world pause of the last GC, GCPauseTotal the sum of all pauses
+	LastGCPause  time.Duration
+	GCPauseTotal time.Duration
+	Goroutines   int
+	// OpenFDs is the number of open file descriptors, or -1 where they cannot be counted
+	OpenFDs int
+}
+
+// ReadSnapshot returns the current runtime statistics. It stops the world briefly, like
+// runtime.ReadMemStats.
+func ReadSnapshot() Snapshot {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	s := Snapshot{
+		Time:         time.Now(),
+		HeapAlloc:    m.HeapAlloc,
+		HeapSys:      m.HeapSys,
+		Sys:          m.Sys,
+		TotalAlloc:   m.TotalAlloc,
+		NumGC:        m.NumGC,
+		GCPauseTotal: time.Duration(m.PauseTotalNs),
+		Goroutines:   runtime.NumGoroutine(),
+		OpenFDs:      openFDs(),
+	}
+	if m.NumGC > 0 {
+		s.LastGCPause = time.Duration(m.PauseNs[(m.NumGC+255)%256])
+	}
+	return s
+}
+
+// StartTicker calls sink with a snapshot at the interval, until the context is cancelled. Unlike
+// StartStatsTicker it leaves it to the caller where the stats go, e.g. to their own telemetry. The
+// interval must be positive.
+func StartTicker(ctx context.Context, interval time.Duration, sink func(Snapshot)) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid ticker interval %v, must be positive", interval)
+	}
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sink(ReadSnapshot())
+			}
+		}
+	}()
+	return nil
+}
@@ -80,3 +80,12 @@ func LogStack() {
 	stacklen := runtime.Stack(buf, true)
 	log.Infof("*** goroutine dump...\n%s\n*** end\n", buf[:stacklen])
 }
+
+// openFDs counts the entries of /proc/self/fd, not including the descriptor used to read it
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries) - 1
+}
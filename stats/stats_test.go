@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollingCounter(t *testing.T) {
+	c := NewRollingCounter(15*time.Minute, 5*time.Second)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	c.Inc()
+	now = now.Add(4 * time.Minute)
+	c.Add(3)
+	now = now.Add(2 * time.Minute)
+	c.Add(2)
+
+	assert.Equal(t, int64(2), c.Count(time.Minute))
+	assert.Equal(t, int64(5), c.Count(5*time.Minute))
+	assert.Equal(t, int64(6), c.Count(15*time.Minute))
+	assert.Equal(t, int64(6), c.Count(time.Hour))
+	assert.InDelta(t, 2.0/60, c.Rate(time.Minute), 1e-9)
+	assert.InDelta(t, 6.0/900, c.Rate(time.Hour), 1e-9)
+
+	// events older than the span are forgotten, even though their buckets were not reused
+	now = now.Add(11 * time.Minute)
+	assert.Equal(t, int64(5), c.Count(15*time.Minute))
+	now = now.Add(15 * time.Minute)
+	assert.Equal(t, int64(0), c.Count(15*time.Minute))
+	assert.Equal(t, 0.0, c.Rate(0))
+}
+
+func TestReadSnapshot(t *testing.T) {
+	runtime.GC()
+	s := ReadSnapshot()
+	assert.NotZero(t, s.HeapAlloc)
+	assert.NotZero(t, s.NumGC)
+	assert.Positive(t, s.Goroutines)
+	if runtime.GOOS == "windows" {
+		assert.Equal(t, -1, s.OpenFDs)
+	} else {
+		// at least stdin, stdout and stderr
+		assert.GreaterOrEqual(t, s.OpenFDs, 3)
+	}
+}
+
+func TestStartTicker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	snapshots := make(chan Snapshot, 10)
+	require.NoError(t, StartTicker(ctx, 10*time.Millisecond, func(s Snapshot) { snapshots <- s }))
+	first := <-snapshots
+	second := <-snapshots
+	assert.True(t, second.Time.After(first.Time))
+
+	require.Error(t, StartTicker(ctx, 0, func(Snapshot) {}))
+}
+
+func TestCollector(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(Collector()))
+	// the standard collectors do not collide with it
+	require.NoError(t, registry.Register(collectors.NewGoCollector()))
+	count, err := testutil.GatherAndCount(registry, "runtime_goroutines", "runtime_gc_pause_seconds_total")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
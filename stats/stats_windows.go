@@ -43,3 +43,8 @@ func LogStack() {
 	stacklen := runtime.Stack(buf, true)
 	log.Infof("*** goroutine dump...\n%s\n*** end\n", buf[:stacklen])
 }
+
+// openFDs is not supported on windows, where handles are not file descriptors
+func openFDs() int {
+	return -1
+}
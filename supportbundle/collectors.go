@@ -0,0 +1,145 @@
+package supportbundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+
+	"github.com/argoproj/pkg/v2/logging"
+	"github.com/argoproj/pkg/v2/stats"
+)
+
+// BuildInfo collects the Go version, main module and dependencies of the binary
+func BuildInfo() Collector {
+	return Collector{Path: "build-info.txt", Collect: func(_ context.Context, w io.Writer) error {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return errors.New("binary was built without module support")
+		}
+		_, err := io.WriteString(w, info.String())
+		return err
+	}}
+}
+
+type runtimeStats struct {
+	Time       time.Time `json:"time"`
+	GoVersion  string    `json:"goVersion"`
+	GOOS       string    `json:"goos"`
+	GOARCH     string    `json:"goarch"`
+	NumCPU     int       `json:"numCPU"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	Goroutines int       `json:"goroutines"`
+	OpenFDs    int       `json:"openFDs"`
+	PID        int       `json:"pid"`
+	Args       []string  `json:"args"`
+	Memory     struct {
+		HeapAlloc    uint64        `json:"heapAlloc"`
+		HeapSys      uint64        `json:"heapSys"`
+		Sys          uint64        `json:"sys"`
+		TotalAlloc   uint64        `json:"totalAlloc"`
+		NumGC        uint32        `json:"numGC"`
+		LastGCPause  time.Duration `json:"lastGCPause"`
+		GCPauseTotal time.Duration `json:"gcPauseTotal"`
+	} `json:"memory"`
+}
+
+// RuntimeStats collects a stats.Snapshot, the platform and the process arguments
+func RuntimeStats() Collector {
+	return Collector{Path: "runtime.json", Collect: func(_ context.Context, w io.Writer) error {
+		s := stats.ReadSnapshot()
+		rs := runtimeStats{
+			Time:       s.Time,
+			GoVersion:  runtime.Version(),
+			GOOS:       runtime.GOOS,
+			GOARCH:     runtime.GOARCH,
+			NumCPU:     runtime.NumCPU(),
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+			Goroutines: s.Goroutines,
+			OpenFDs:    s.OpenFDs,
+			PID:        os.Getpid(),
+			Args:       os.Args,
+		}
+		rs.Memory.HeapAlloc = s.HeapAlloc
+		rs.Memory.HeapSys = s.HeapSys
+		rs.Memory.Sys = s.Sys
+		rs.Memory.TotalAlloc = s.TotalAlloc
+		rs.Memory.NumGC = s.NumGC
+		rs.Memory.LastGCPause = s.LastGCPause
+		rs.Memory.GCPauseTotal = s.GCPauseTotal
+		return writeJSON(w, rs)
+	}}
+}
+
+// Logs collects the entries captured by the ring buffer as JSON lines, oldest first
+func Logs(rb *logging.RingBuffer) Collector {
+	return Collector{Path: "logs.jsonl", Collect: func(_ context.Context, w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, e := range rb.Entries() {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// Goroutines collects the stack traces of all goroutines
+func Goroutines() Collector {
+	return Collector{Path: "goroutines.txt", Collect: func(_ context.Context, w io.Writer) error {
+		return pprof.Lookup("goroutine").WriteTo(w, 2)
+	}}
+}
+
+// HeapProfile collects a heap profile after running a garbage collection
+func HeapProfile() Collector {
+	return Collector{Path: "heap.pprof", Binary: true, Collect: func(_ context.Context, w io.Writer) error {
+		runtime.GC()
+		return pprof.WriteHeapProfile(w)
+	}}
+}
+
+// CPUProfile collects a CPU profile of the given duration. It fails if another CPU profile is
+// already running.
+func CPUProfile(d time.Duration) Collector {
+	return Collector{Path: "cpu.pprof", Binary: true, Collect: func(ctx context.Context, w io.Writer) error {
+		if err := pprof.StartCPUProfile(w); err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+			return nil
+		}
+	}}
+}
+
+// JSON collects v encoded as indented JSON, typically the configuration of the binary. Configure a
+// Redactor in the options to mask its secrets.
+func JSON(path string, v interface{}) Collector {
+	return Collector{Path: path, Collect: func(_ context.Context, w io.Writer) error {
+		return writeJSON(w, v)
+	}}
+}
+
+// Text collects the string returned by fn, for ad-hoc diagnostics
+func Text(path string, fn func() string) Collector {
+	return Collector{Path: path, Collect: func(_ context.Context, w io.Writer) error {
+		_, err := fmt.Fprintln(w, fn())
+		return err
+	}}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,131 @@
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/argoproj/pkg/v2/logging"
+	"github.com/argoproj/pkg/v2/redact"
+)
+
+// Collector contributes a single file to the bundle
+type Collector struct {
+	// Path of the file within the bundle
+	Path string
+	// Collect writes the contents of the file
+	Collect func(ctx context.Context, w io.Writer) error
+	// Binary files, such as profiles, are not redacted
+	Binary bool
+}
+
+// Options configures a Bundle
+type Options struct {
+	// Redactor masks secrets in all non-binary files. Nothing is masked if nil.
+	Redactor *redact.Redactor
+	// Profiles adds a heap profile and a goroutine dump to the default collectors
+	Profiles bool
+	// CPUProfileDuration adds a CPU profile of the given duration if positive
+	CPUProfileDuration time.Duration
+	// Logs adds the recent log entries captured by the ring buffer
+	Logs *logging.RingBuffer
+}
+
+// Bundle collects diagnostics from registered collectors into a single tar.gz archive
+type Bundle struct {
+	opts       Options
+	lock       sync.Mutex
+	collectors []Collector
+}
+
+// New returns a Bundle with the default collectors for build info and runtime statistics, and
+// the logs and profiles enabled in the options
+func New(opts Options) *Bundle {
+	b := &Bundle{opts: opts}
+	b.Register(BuildInfo())
+	b.Register(RuntimeStats())
+	if opts.Logs != nil {
+		b.Register(Logs(opts.Logs))
+	}
+	if opts.Profiles {
+		b.Register(Goroutines())
+		b.Register(HeapProfile())
+	}
+	if opts.CPUProfileDuration > 0 {
+		b.Register(CPUProfile(opts.CPUProfileDuration))
+	}
+	return b
+}
+
+// Register adds a collector. Collectors are run in the order they were registered.
+func (b *Bundle) Register(c Collector) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.collectors = append(b.collectors, c)
+}
+
+// Write runs all collectors and writes the archive to w. A failing collector does not fail the
+// bundle; its error is written to "<path>.error" instead, so that a partial bundle is still useful.
+func (b *Bundle) Write(ctx context.Context, w io.Writer) error {
+	b.lock.Lock()
+	collectors := append([]Collector(nil), b.collectors...)
+	b.lock.Unlock()
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, c := range collectors {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		path := c.Path
+		var buf bytes.Buffer
+		if err := c.Collect(ctx, &buf); err != nil {
+			logging.FromContext(ctx).WithField("path", c.Path).Warnf("support bundle collector failed: %v", err)
+			path += ".error"
+			buf.Reset()
+			buf.WriteString(err.Error() + "\n")
+		}
+		data := buf.Bytes()
+		if !c.Binary && b.opts.Redactor != nil {
+			data = b.opts.Redactor.Redact(data)
+		}
+		hdr := &tar.Header{Name: path, Mode: 0o644, Size: int64(len(data)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Handler returns an HTTP handler which serves the bundle as a download. Collecting a bundle, in
+// particular a CPU profile, may take longer than the write timeout of the server, so the write
+// deadline of the response is cleared.
+func (b *Bundle) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logging.FromContext(r.Context()).Warnf("failed to clear write deadline of support bundle: %v", err)
+		}
+		name := fmt.Sprintf("support-bundle-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		if err := b.Write(r.Context(), w); err != nil {
+			// the response has already started, so the best we can do is to truncate it
+			logging.FromContext(r.Context()).Warnf("failed to write support bundle: %v", err)
+		}
+	})
+}
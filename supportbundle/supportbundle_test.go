@@ -0,0 +1,99 @@
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/argoproj/pkg/v2/logging"
+	"github.com/argoproj/pkg/v2/redact"
+)
+
+func readBundle(t *testing.T, r io.Reader) map[string]string {
+	gz, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(data)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	rb := logging.NewRingBuffer(10)
+	logging.New(rb, nil).Infof("logged in with password hunter2")
+	b := New(Options{
+		Redactor: redact.New(redact.Options{Secrets: []string{"hunter2"}}),
+		Profiles: true,
+		Logs:     rb,
+	})
+	b.Register(JSON("config.json", map[string]string{"user": "admin", "password": "hunter2"}))
+	b.Register(Text("notes.txt", func() string { return "hello" }))
+	b.Register(Collector{Path: "broken.txt", Collect: func(context.Context, io.Writer) error {
+		return errors.New("boom")
+	}})
+
+	var buf bytes.Buffer
+	require.NoError(t, b.Write(context.Background(), &buf))
+	files := readBundle(t, &buf)
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"build-info.txt", "runtime.json", "logs.jsonl", "goroutines.txt", "heap.pprof", "config.json", "notes.txt", "broken.txt.error"}, names)
+	assert.JSONEq(t, `{"user":"admin","password":"******"}`, files["config.json"])
+	assert.Equal(t, "hello\n", files["notes.txt"])
+	assert.Contains(t, files["logs.jsonl"], `"msg":"logged in with password ******"`)
+	assert.Equal(t, "boom\n", files["broken.txt.error"])
+	assert.Contains(t, files["runtime.json"], `"goroutines"`)
+	assert.Contains(t, files["runtime.json"], `"gcPauseTotal"`)
+	assert.Contains(t, files["goroutines.txt"], "TestBundle")
+	assert.NotEmpty(t, files["heap.pprof"])
+}
+
+func TestHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	New(Options{}).Handler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/support-bundle", nil))
+	assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "support-bundle-")
+	files := readBundle(t, w.Body)
+	assert.Len(t, files, 2)
+}
+
+func TestHandlerWriteTimeout(t *testing.T) {
+	b := New(Options{})
+	b.Register(Collector{Path: "slow.txt", Collect: func(ctx context.Context, w io.Writer) error {
+		time.Sleep(200 * time.Millisecond)
+		_, err := io.WriteString(w, "done")
+		return err
+	}})
+	srv := httptest.NewUnstartedServer(b.Handler())
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	// the bundle takes longer than the write timeout of the server
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	files := readBundle(t, resp.Body)
+	assert.Equal(t, "done", files["slow.txt"])
+}
@@ -1,6 +1,13 @@
 package sync
 
-import "sync"
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
 
 type KeyLock interface {
 	Lock(key string)
@@ -9,51 +16,236 @@ type KeyLock interface {
 	RUnlock(key string)
 }
 
+// PriorityKeyLock is a KeyLock which can also acquire locks ahead of other waiters and list the held
+// locks
+type PriorityKeyLock interface {
+	KeyLock
+	// LockWithPriority and RLockWithPriority acquire the lock before all waiters with a lower
+	// priority. Lock and RLock wait with priority 0, waiters with the same priority are served in
+	// order of arrival.
+	LockWithPriority(key string, priority int)
+	RLockWithPriority(key string, priority int)
+	// Held returns the currently held locks sorted by key, e.g. for debug endpoints
+	Held() []HeldLock
+}
+
+// HeldLock describes a held lock
+type HeldLock struct {
+	Key string
+	// Write is set for write locks, otherwise Readers is the number of read locks
+	Write   bool
+	Readers int
+	// Waiters is the number of goroutines waiting for the lock
+	Waiters int
+	// Since is when the lock was acquired, for read locks by the first of the current readers
+	Since    time.Time
+	Duration time.Duration
+}
+
+// KeyLockOptions configures a key lock
+type KeyLockOptions struct {
+	// TTL releases locks held for longer, to recover from goroutines which crashed or returned
+	// without unlocking in long-running processes. Each write lock and each read lock expires on
+	// its own. Locks do not know their holder, so a late Unlock of an expired lock releases whoever
+	// holds the lock by then, and panics like any unlock of an unlocked key if nobody does. The TTL
+	// must therefore be far above the longest legitimate hold time. Zero disables expiry.
+	TTL time.Duration
+	// OnExpire is called with the key and how long it was held when a lock is released because
+	// its TTL expired
+	OnExpire func(key string, held time.Duration)
+	// Logger logs expired locks. It defaults to the logger of the logging package.
+	Logger logging.Logger
+}
+
 type keyLock struct {
-	guard sync.RWMutex
-	locks map[string]*sync.RWMutex
+	opts KeyLockOptions
+	now  func() time.Time
+
+	guard sync.Mutex
+	locks map[string]*keyState
+}
+
+type keyState struct {
+	readers int
+	writer  bool
+	// holds has one entry for the writer or for each reader, in order of acquisition
+	holds   []*keyHold
+	waiters []*keyWaiter
+}
+
+// keyHold is a single acquisition of a lock, with its own expiry timer
+type keyHold struct {
+	since time.Time
+	timer *time.Timer
+}
+
+type keyWaiter struct {
+	write    bool
+	priority int
+	ready    chan struct{}
 }
 
 func NewKeyLock() KeyLock {
+	return NewKeyLockWithOptions(KeyLockOptions{})
+}
+
+// NewKeyLockWithOptions returns a key lock with the given options
+func NewKeyLockWithOptions(opts KeyLockOptions) PriorityKeyLock {
+	if opts.Logger == nil {
+		opts.Logger = logging.FromContext(context.Background())
+	}
 	return &keyLock{
-		guard: sync.RWMutex{},
-		locks: map[string]*sync.RWMutex{},
+		opts:  opts,
+		now:   time.Now,
+		locks: map[string]*keyState{},
 	}
 }
 
-func (l *keyLock) getLock(key string) *sync.RWMutex {
-	l.guard.RLock()
-	if lock, ok := l.locks[key]; ok {
-		l.guard.RUnlock()
-		return lock
+func (l *keyLock) acquire(key string, write bool, priority int) {
+	l.guard.Lock()
+	state, ok := l.locks[key]
+	if !ok {
+		state = &keyState{}
+		l.locks[key] = state
 	}
+	w := &keyWaiter{write: write, priority: priority, ready: make(chan struct{})}
+	// insert after all waiters with the same or a higher priority
+	i := sort.Search(len(state.waiters), func(i int) bool { return state.waiters[i].priority < priority })
+	state.waiters = append(state.waiters, nil)
+	copy(state.waiters[i+1:], state.waiters[i:])
+	state.waiters[i] = w
+	l.dispatch(key, state)
+	l.guard.Unlock()
+	<-w.ready
+}
+
+// dispatch grants the lock to the waiters at the head of the queue for as long as possible. Readers
+// queue behind waiting writers, so writers are not starved.
+func (l *keyLock) dispatch(key string, state *keyState) {
+	for len(state.waiters) > 0 {
+		w := state.waiters[0]
+		if state.writer || (w.write && state.readers > 0) {
+			return
+		}
+		l.hold(key, state)
+		if w.write {
+			state.writer = true
+		} else {
+			state.readers++
+		}
+		state.waiters = state.waiters[1:]
+		close(w.ready)
+	}
+}
 
-	l.guard.RUnlock()
+// hold records an acquisition of the lock and starts its expiry timer
+func (l *keyLock) hold(key string, state *keyState) {
+	h := &keyHold{since: l.now()}
+	if l.opts.TTL > 0 {
+		h.timer = time.AfterFunc(l.opts.TTL, func() {
+			l.expire(key, h)
+		})
+	}
+	state.holds = append(state.holds, h)
+}
+
+func (l *keyLock) release(key string, write bool) {
 	l.guard.Lock()
+	defer l.guard.Unlock()
+	state, ok := l.locks[key]
+	if !ok || (write && !state.writer) || (!write && state.readers == 0) {
+		panic("sync: unlock of unlocked key " + key)
+	}
+	// readers are indistinguishable, so the latest hold is dropped. The oldest one keeps running,
+	// so that a leaked read lock still expires while other readers come and go.
+	l.unhold(key, state, len(state.holds)-1)
+}
 
-	if lock, ok := l.locks[key]; ok {
-		l.guard.Unlock()
-		return lock
+// unhold releases the i-th hold, hands the lock to the next waiters, and forgets the key when it is
+// no longer used
+func (l *keyLock) unhold(key string, state *keyState, i int) {
+	if timer := state.holds[i].timer; timer != nil {
+		timer.Stop()
 	}
+	state.holds = append(state.holds[:i], state.holds[i+1:]...)
+	if state.writer {
+		state.writer = false
+	} else {
+		state.readers--
+	}
+	l.dispatch(key, state)
+	if !state.writer && state.readers == 0 && len(state.waiters) == 0 {
+		delete(l.locks, key)
+	}
+}
 
-	lock := &sync.RWMutex{}
-	l.locks[key] = lock
+func (l *keyLock) expire(key string, h *keyHold) {
+	l.guard.Lock()
+	state, ok := l.locks[key]
+	i := -1
+	if ok {
+		for j, held := range state.holds {
+			if held == h {
+				i = j
+			}
+		}
+	}
+	if i < 0 {
+		// released in the meantime
+		l.guard.Unlock()
+		return
+	}
+	held := l.now().Sub(h.since)
+	l.unhold(key, state, i)
 	l.guard.Unlock()
-	return lock
+	l.opts.Logger.WithField("key", key).Warnf("Released lock held for %v after its TTL expired", held)
+	if l.opts.OnExpire != nil {
+		l.opts.OnExpire(key, held)
+	}
 }
 
 func (l *keyLock) Lock(key string) {
-	l.getLock(key).Lock()
+	l.acquire(key, true, 0)
 }
 
 func (l *keyLock) Unlock(key string) {
-	l.getLock(key).Unlock()
+	l.release(key, true)
 }
 
 func (l *keyLock) RLock(key string) {
-	l.getLock(key).RLock()
+	l.acquire(key, false, 0)
 }
 
 func (l *keyLock) RUnlock(key string) {
-	l.getLock(key).RUnlock()
+	l.release(key, false)
+}
+
+func (l *keyLock) LockWithPriority(key string, priority int) {
+	l.acquire(key, true, priority)
+}
+
+func (l *keyLock) RLockWithPriority(key string, priority int) {
+	l.acquire(key, false, priority)
+}
+
+func (l *keyLock) Held() []HeldLock {
+	l.guard.Lock()
+	defer l.guard.Unlock()
+	now := l.now()
+	var held []HeldLock
+	for key, state := range l.locks {
+		if !state.writer && state.readers == 0 {
+			continue
+		}
+		held = append(held, HeldLock{
+			Key:      key,
+			Write:    state.writer,
+			Readers:  state.readers,
+			Waiters:  len(state.waiters),
+			Since:    state.holds[0].since,
+			Duration: now.Sub(state.holds[0].since),
+		})
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].Key < held[j].Key })
+	return held
 }
@@ -3,8 +3,10 @@ package sync
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLockLock(t *testing.T) {
@@ -107,3 +109,93 @@ func TestRLockRLock(t *testing.T) {
 	l.RUnlock("my-key")
 	l.RUnlock("my-key")
 }
+
+func TestLockPriority(t *testing.T) {
+	l := NewKeyLockWithOptions(KeyLockOptions{})
+	l.Lock("my-key")
+
+	order := make(chan string, 3)
+	wg := sync.WaitGroup{}
+	for i, w := range []struct {
+		name     string
+		priority int
+	}{{"low", 0}, {"high", 10}, {"medium", 5}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.LockWithPriority("my-key", w.priority)
+			order <- w.name
+			l.Unlock("my-key")
+		}()
+		// wait for the goroutine to queue, so that arrival order is deterministic
+		assert.Eventually(t, func() bool { return l.Held()[0].Waiters == i+1 }, time.Second, time.Millisecond)
+	}
+	l.Unlock("my-key")
+	wg.Wait()
+	close(order)
+	var names []string
+	for name := range order {
+		names = append(names, name)
+	}
+	assert.Equal(t, []string{"high", "medium", "low"}, names)
+	assert.Empty(t, l.Held())
+}
+
+func TestLockTTL(t *testing.T) {
+	expired := make(chan string, 1)
+	l := NewKeyLockWithOptions(KeyLockOptions{
+		TTL:      50 * time.Millisecond,
+		OnExpire: func(key string, _ time.Duration) { expired <- key },
+	})
+	l.Lock("my-key")
+	// the lock is released by the TTL although it is never unlocked
+	l.Lock("my-key")
+	assert.Equal(t, "my-key", <-expired)
+	l.Unlock("my-key")
+	// a late unlock of an expired lock which nobody holds panics
+	assert.Panics(t, func() { l.Unlock("my-key") })
+
+	l.RLock("other-key")
+	l.RUnlock("other-key")
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, expired)
+}
+
+func TestRLockTTL(t *testing.T) {
+	expired := make(chan string, 2)
+	l := NewKeyLockWithOptions(KeyLockOptions{
+		TTL:      100 * time.Millisecond,
+		OnExpire: func(key string, _ time.Duration) { expired <- key },
+	})
+	// a leaked read lock
+	l.RLock("my-key")
+	time.Sleep(60 * time.Millisecond)
+	l.RLock("my-key")
+	// only the leaked read lock expires, the later reader keeps its lock
+	assert.Equal(t, "my-key", <-expired)
+	require.Len(t, l.Held(), 1)
+	assert.Equal(t, 1, l.Held()[0].Readers)
+	l.RUnlock("my-key")
+	assert.Empty(t, l.Held())
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, expired)
+}
+
+func TestHeld(t *testing.T) {
+	l := NewKeyLockWithOptions(KeyLockOptions{}).(*keyLock)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+	l.Lock("b")
+	l.RLock("a")
+	l.RLock("a")
+	now = now.Add(time.Minute)
+	assert.Equal(t, []HeldLock{
+		{Key: "a", Readers: 2, Since: time.Unix(1000, 0), Duration: time.Minute},
+		{Key: "b", Write: true, Since: time.Unix(1000, 0), Duration: time.Minute},
+	}, l.Held())
+	l.Unlock("b")
+	l.RUnlock("a")
+	l.RUnlock("a")
+	assert.Empty(t, l.Held())
+	assert.Panics(t, func() { l.Unlock("b") })
+}
@@ -0,0 +1,314 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRenewInterval = 2 * time.Second
+	DefaultRetryInterval = 2 * time.Second
+)
+
+// ErrLeaseLost is the cause of the cancellation of LeaseHandle.Context when the lease could not be
+// renewed in time, or was taken over by another holder
+var ErrLeaseLost = errors.New("lease lost")
+
+// LeaseLockOptions configures a LeaseLock. Zero values are replaced by the defaults above.
+type LeaseLockOptions struct {
+	// Identity identifies the holder, e.g. the pod name. It must be unique among the processes
+	// competing for the lock.
+	Identity string
+	// LeaseDuration is how long the lock is held without being renewed. It is rounded up to whole
+	// seconds.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long after the last successful renewal the holder gives up the lock. It
+	// must be below LeaseDuration, so that the holder stops before a contender can take over.
+	RenewDeadline time.Duration
+	// RenewInterval is how often the holder renews the lease. It must be well below RenewDeadline.
+	RenewInterval time.Duration
+	// RetryInterval is how often Lock tries to acquire a lock held by someone else
+	RetryInterval time.Duration
+	// OnLost is called from the renew goroutine when the lock is lost
+	OnLost func(err error)
+}
+
+// LeaseLock is a distributed lock backed by a coordination.k8s.io Lease, for mutual exclusion
+// between processes in different pods. A lock is only held while it is renewed, so holders must
+// stop working on the protected resource when LeaseHandle.Context is done. Writes to external
+// systems should carry the fencing token, so that a holder which lost the lock without noticing
+// cannot overwrite the work of its successor.
+//
+// To avoid relying on synchronized clocks, a lease held by someone else is only considered expired
+// after this process has observed it unchanged for the lease duration. A restarted process
+// therefore waits up to one lease duration for a lease left behind by its predecessor.
+type LeaseLock struct {
+	client    coordinationv1client.LeasesGetter
+	namespace string
+	name      string
+	opts      LeaseLockOptions
+	now       func() time.Time
+
+	lock     sync.Mutex
+	held     *LeaseHandle
+	observed observedLease
+}
+
+type observedLease struct {
+	holder      string
+	renewTime   time.Time
+	transitions int32
+	at          time.Time
+}
+
+// NewLeaseLock returns a LeaseLock for the Lease with the given namespace and name. The Lease is
+// created when the lock is first acquired.
+func NewLeaseLock(client kubernetes.Interface, namespace, name string, opts LeaseLockOptions) *LeaseLock {
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = DefaultLeaseDuration
+	}
+	if opts.RenewDeadline == 0 {
+		opts.RenewDeadline = min(DefaultRenewDeadline, opts.LeaseDuration*2/3)
+	}
+	if opts.RenewInterval == 0 {
+		opts.RenewInterval = min(DefaultRenewInterval, opts.RenewDeadline/4)
+	}
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = DefaultRetryInterval
+	}
+	return &LeaseLock{
+		client:    client.CoordinationV1(),
+		namespace: namespace,
+		name:      name,
+		opts:      opts,
+		now:       time.Now,
+	}
+}
+
+// Lock blocks until the lock is acquired or the context is done
+func (l *LeaseLock) Lock(ctx context.Context) (*LeaseHandle, error) {
+	for {
+		handle, ok, err := l.TryLock(ctx)
+		if err != nil || ok {
+			return handle, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(l.opts.RetryInterval):
+		}
+	}
+}
+
+// TryLock acquires the lock if it is free, and returns false if it is held by someone else,
+// including another handle of this LeaseLock
+func (l *LeaseLock) TryLock(ctx context.Context) (*LeaseHandle, bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.held != nil {
+		return nil, false, nil
+	}
+	now := l.now()
+	lease, err := l.client.Leases(l.namespace).Get(ctx, l.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Namespace: l.namespace, Name: l.name}}
+		l.acquire(lease, now)
+		lease, err = l.client.Leases(l.namespace).Create(ctx, lease, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return nil, false, nil
+		}
+	} else if err == nil {
+		if !l.expired(lease, now) {
+			return nil, false, nil
+		}
+		l.acquire(lease, now)
+		lease, err = l.client.Leases(l.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+		if apierrors.IsConflict(err) {
+			return nil, false, nil
+		}
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lease %s/%s: %w", l.namespace, l.name, err)
+	}
+	l.held = newLeaseHandle(ctx, l, int64(*lease.Spec.LeaseTransitions), now)
+	return l.held, true, nil
+}
+
+// expired returns whether the lease is free or was not renewed for its duration
+func (l *LeaseLock) expired(lease *coordinationv1.Lease, now time.Time) bool {
+	holder := ptr.Deref(lease.Spec.HolderIdentity, "")
+	if holder == "" {
+		return true
+	}
+	observed := observedLease{holder: holder, transitions: ptr.Deref(lease.Spec.LeaseTransitions, 0)}
+	if lease.Spec.RenewTime != nil {
+		observed.renewTime = lease.Spec.RenewTime.Time
+	}
+	if observed.holder != l.observed.holder || observed.transitions != l.observed.transitions || !observed.renewTime.Equal(l.observed.renewTime) {
+		observed.at = now
+		l.observed = observed
+	}
+	duration := time.Duration(ptr.Deref(lease.Spec.LeaseDurationSeconds, 0)) * time.Second
+	return now.Sub(l.observed.at) >= duration
+}
+
+func (l *LeaseLock) acquire(lease *coordinationv1.Lease, now time.Time) {
+	seconds := int32((l.opts.LeaseDuration + time.Second - 1) / time.Second)
+	lease.Spec.HolderIdentity = ptr.To(l.opts.Identity)
+	lease.Spec.LeaseDurationSeconds = ptr.To(seconds)
+	lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+	lease.Spec.LeaseTransitions = ptr.To(ptr.Deref(lease.Spec.LeaseTransitions, 0) + 1)
+}
+
+// get returns the lease if it is still held by the handle with the token
+func (l *LeaseLock) get(ctx context.Context, token int64) (*coordinationv1.Lease, error) {
+	lease, err := l.client.Leases(l.namespace).Get(ctx, l.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if ptr.Deref(lease.Spec.HolderIdentity, "") != l.opts.Identity || int64(ptr.Deref(lease.Spec.LeaseTransitions, 0)) != token {
+		return nil, fmt.Errorf("%w: lease %s/%s was taken over by %q", ErrLeaseLost, l.namespace, l.name, ptr.Deref(lease.Spec.HolderIdentity, ""))
+	}
+	return lease, nil
+}
+
+// renew updates the renew time of the lease and returns it
+func (l *LeaseLock) renew(ctx context.Context, token int64) (time.Time, error) {
+	lease, err := l.get(ctx, token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := l.now()
+	lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+	_, err = l.client.Leases(l.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	return now, err
+}
+
+func (l *LeaseLock) release(ctx context.Context, token int64) error {
+	lease, err := l.get(ctx, token)
+	if errors.Is(err, ErrLeaseLost) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.HolderIdentity = nil
+	lease.Spec.RenewTime = nil
+	_, err = l.client.Leases(l.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	return err
+}
+
+func (l *LeaseLock) forget(handle *LeaseHandle) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.held == handle {
+		l.held = nil
+	}
+}
+
+// LeaseHandle is a held LeaseLock
+type LeaseHandle struct {
+	lock    *LeaseLock
+	token   int64
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	stopped chan struct{}
+}
+
+func newLeaseHandle(ctx context.Context, lock *LeaseLock, token int64, acquired time.Time) *LeaseHandle {
+	// the handle outlives the context of the call which acquired it, but keeps its logger
+	hctx, cancel := context.WithCancelCause(logging.NewContext(context.Background(), logging.FromContext(ctx)))
+	h := &LeaseHandle{lock: lock, token: token, ctx: hctx, cancel: cancel, stopped: make(chan struct{})}
+	go h.renewLoop(acquired)
+	return h
+}
+
+// Token returns the fencing token, which increases every time the lock is acquired
+func (h *LeaseHandle) Token() int64 {
+	return h.token
+}
+
+// Context returns a context which is done when the lock is lost or released. Its cause is
+// ErrLeaseLost if the lock was lost.
+func (h *LeaseHandle) Context() context.Context {
+	return h.ctx
+}
+
+// Unlock stops renewing the lease and releases it, so that other processes can acquire it without
+// waiting for it to expire
+func (h *LeaseHandle) Unlock(ctx context.Context) error {
+	h.cancel(nil)
+	<-h.stopped
+	defer h.lock.forget(h)
+	if errors.Is(context.Cause(h.ctx), ErrLeaseLost) {
+		return nil
+	}
+	return h.lock.release(ctx, h.token)
+}
+
+// renewLoop renews the lease until the handle is released. The lock is given up as soon as the
+// renew deadline has passed since the renew time of the last successful update, which is no later
+// than when a contender may consider the lease expired.
+func (h *LeaseHandle) renewLoop(lastRenew time.Time) {
+	defer close(h.stopped)
+	opts := h.lock.opts
+	logger := logging.FromContext(h.ctx).WithFields(logging.Fields{"lease": h.lock.namespace + "/" + h.lock.name, "token": h.token})
+	ticker := time.NewTicker(opts.RenewInterval)
+	defer ticker.Stop()
+	untilDeadline := func() time.Duration { return lastRenew.Add(opts.RenewDeadline).Sub(h.lock.now()) }
+	deadline := time.NewTimer(untilDeadline())
+	defer deadline.Stop()
+	var lastErr error
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-deadline.C:
+			h.lose(fmt.Errorf("%w: not renewed within %v: %v", ErrLeaseLost, opts.RenewDeadline, lastErr), logger)
+			return
+		case <-ticker.C:
+		}
+		// the update must not succeed after the deadline
+		ctx, cancel := context.WithTimeout(h.ctx, untilDeadline())
+		renewed, err := h.lock.renew(ctx, h.token)
+		cancel()
+		if h.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, ErrLeaseLost) {
+			h.lose(err, logger)
+			return
+		}
+		if err != nil {
+			lastErr = err
+			logger.Warnf("Failed to renew lease: %v", err)
+			continue
+		}
+		lastRenew = renewed
+		deadline.Reset(untilDeadline())
+	}
+}
+
+func (h *LeaseHandle) lose(err error, logger logging.Logger) {
+	logger.Errorf("Lost lease: %v", err)
+	h.cancel(err)
+	h.lock.forget(h)
+	if h.lock.opts.OnLost != nil {
+		h.lock.opts.OnLost(err)
+	}
+}
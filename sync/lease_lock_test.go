@@ -0,0 +1,180 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestLeaseLock(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	a := NewLeaseLock(client, "argo", "lock", LeaseLockOptions{Identity: "a"})
+	b := NewLeaseLock(client, "argo", "lock", LeaseLockOptions{Identity: "b"})
+
+	handle, ok, err := a.TryLock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), handle.Token())
+	_, ok, err = a.TryLock(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = b.TryLock(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, handle.Unlock(ctx))
+	assert.Equal(t, context.Canceled, context.Cause(handle.Context()))
+	handle, err = b.Lock(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), handle.Token())
+	lease, err := client.CoordinationV1().Leases("argo").Get(ctx, "lock", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "b", *lease.Spec.HolderIdentity)
+	assert.Equal(t, int32(15), *lease.Spec.LeaseDurationSeconds)
+	require.NoError(t, handle.Unlock(ctx))
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	handle, err = a.Lock(ctx)
+	require.NoError(t, err)
+	_, err = b.Lock(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, handle.Unlock(context.Background()))
+}
+
+func TestLeaseLockExpired(t *testing.T) {
+	ctx := context.Background()
+	// a lease left behind by a crashed holder
+	client := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "argo", Name: "lock"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("crashed"),
+			LeaseDurationSeconds: ptr.To(int32(15)),
+			RenewTime:            &metav1.MicroTime{Time: time.Now()},
+			LeaseTransitions:     ptr.To(int32(3)),
+		},
+	})
+	l := NewLeaseLock(client, "argo", "lock", LeaseLockOptions{Identity: "a"})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	_, ok, err := l.TryLock(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	now = now.Add(10 * time.Second)
+	_, ok, err = l.TryLock(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	now = now.Add(5 * time.Second)
+	handle, ok, err := l.TryLock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, int64(4), handle.Token())
+	require.NoError(t, handle.Unlock(ctx))
+}
+
+func TestLeaseLockLost(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	lost := make(chan error, 1)
+	l := NewLeaseLock(client, "argo", "lock", LeaseLockOptions{
+		Identity:      "a",
+		RenewInterval: 10 * time.Millisecond,
+		OnLost:        func(err error) { lost <- err },
+	})
+	handle, ok, err := l.TryLock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// the lease is renewed
+	time.Sleep(50 * time.Millisecond)
+	lease, err := client.CoordinationV1().Leases("argo").Get(ctx, "lock", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.True(t, lease.Spec.RenewTime.After(lease.Spec.AcquireTime.Time))
+	require.NoError(t, handle.Context().Err())
+
+	lease.Spec.HolderIdentity = ptr.To("b")
+	lease.Spec.LeaseTransitions = ptr.To(int32(2))
+	_, err = client.CoordinationV1().Leases("argo").Update(ctx, lease, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	select {
+	case err := <-lost:
+		require.ErrorIs(t, err, ErrLeaseLost)
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease loss was not detected")
+	}
+	<-handle.Context().Done()
+	assert.True(t, errors.Is(context.Cause(handle.Context()), ErrLeaseLost))
+	// releasing a lost lease leaves the new holder alone
+	require.NoError(t, handle.Unlock(ctx))
+	lease, err = client.CoordinationV1().Leases("argo").Get(ctx, "lock", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "b", *lease.Spec.HolderIdentity)
+}
+
+func TestLeaseLockRenewDeadline(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	l := NewLeaseLock(client, "argo", "lock", LeaseLockOptions{
+		Identity:      "a",
+		LeaseDuration: time.Second,
+		RenewDeadline: 200 * time.Millisecond,
+		RenewInterval: 20 * time.Millisecond,
+	})
+	handle, ok, err := l.TryLock(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	client.PrependReactor("update", "leases", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("unavailable")
+	})
+	start := time.Now()
+	select {
+	case <-handle.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock was not given up after the renew deadline")
+	}
+	// the lock is given up at the deadline, well before the lease expires
+	assert.Less(t, time.Since(start), 800*time.Millisecond)
+	assert.True(t, errors.Is(context.Cause(handle.Context()), ErrLeaseLost))
+}
+
+func TestLeaseSemaphore(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	var handles []*LeaseHandle
+	for _, identity := range []string{"a", "b"} {
+		s := NewLeaseSemaphore(client, "argo", "semaphore", 2, LeaseLockOptions{Identity: identity})
+		handle, ok, err := s.TryAcquire(ctx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		handles = append(handles, handle)
+	}
+	c := NewLeaseSemaphore(client, "argo", "semaphore", 2, LeaseLockOptions{Identity: "c"})
+	_, ok, err := c.TryAcquire(ctx)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, handles[1].Unlock(ctx))
+	handle, err := c.Acquire(ctx)
+	require.NoError(t, err)
+	lease, err := client.CoordinationV1().Leases("argo").Get(ctx, "semaphore-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "c", *lease.Spec.HolderIdentity)
+	require.NoError(t, handle.Unlock(ctx))
+	require.NoError(t, handles[0].Unlock(ctx))
+
+	assert.Equal(t, DefaultRetryInterval, c.retryInterval)
+	assert.Panics(t, func() { NewLeaseSemaphore(client, "argo", "empty", 0, LeaseLockOptions{}) })
+}
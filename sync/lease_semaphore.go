@@ -0,0 +1,60 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// LeaseSemaphore is a distributed counting semaphore, which allows up to a limit of holders at a
+// time. Each permit is a LeaseLock on a Lease named after the semaphore and the permit index, so
+// all processes must use the same limit.
+type LeaseSemaphore struct {
+	permits       []*LeaseLock
+	retryInterval time.Duration
+}
+
+// NewLeaseSemaphore returns a LeaseSemaphore with the given number of permits, backed by the Leases
+// <name>-0 to <name>-<limit-1> in the namespace. It panics if limit is below 1.
+func NewLeaseSemaphore(client kubernetes.Interface, namespace, name string, limit int, opts LeaseLockOptions) *LeaseSemaphore {
+	if limit < 1 {
+		panic(fmt.Sprintf("sync: lease semaphore %s needs a limit of at least 1, got %d", name, limit))
+	}
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = DefaultRetryInterval
+	}
+	s := &LeaseSemaphore{retryInterval: opts.RetryInterval}
+	for i := 0; i < limit; i++ {
+		s.permits = append(s.permits, NewLeaseLock(client, namespace, fmt.Sprintf("%s-%d", name, i), opts))
+	}
+	return s
+}
+
+// Acquire blocks until a permit is acquired or the context is done
+func (s *LeaseSemaphore) Acquire(ctx context.Context) (*LeaseHandle, error) {
+	for {
+		handle, ok, err := s.TryAcquire(ctx)
+		if err != nil || ok {
+			return handle, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(s.retryInterval):
+		}
+	}
+}
+
+// TryAcquire acquires a permit if one is free. The permit is released with LeaseHandle.Unlock.
+// Fencing tokens are only comparable between handles of the same permit.
+func (s *LeaseSemaphore) TryAcquire(ctx context.Context) (*LeaseHandle, bool, error) {
+	for _, permit := range s.permits {
+		handle, ok, err := permit.TryLock(ctx)
+		if err != nil || ok {
+			return handle, ok, err
+		}
+	}
+	return nil, false, nil
+}
@@ -3,12 +3,17 @@ package time
 import (
 	"fmt"
 	"log"
+	"math"
 	"regexp"
 	"strconv"
 	"time"
 )
 
-var durationRegex = regexp.MustCompile(`^(\d+)([smhd])$`)
+var (
+	durationRegex = regexp.MustCompile(`^(\d+)([smhd])$`)
+	// humanDurationRegex matches one component of a human duration, e.g. 1.5d
+	humanDurationRegex = regexp.MustCompile(`^(\d+(?:\.\d*)?|\.\d+)(ns|us|µs|ms|s|m|h|d|w)`)
+)
 
 // ParseDuration parses a duration string and returns the time.Duration
 func ParseDuration(duration string) (*time.Duration, error) {
@@ -44,3 +49,41 @@ func ParseSince(duration string) (*time.Time, error) {
 	since := time.Now().UTC().Add(-*dur)
 	return &since, nil
 }
+
+// ParseHumanDuration parses a sequence of decimal numbers with units, such as "1d2h30m" or "1.5w".
+// It accepts the units of time.ParseDuration plus d (24 hours) and w (7 days), so it accepts
+// everything ParseDuration and time.ParseDuration do, except negative durations.
+func ParseHumanDuration(duration string) (time.Duration, error) {
+	if duration == "" {
+		return 0, fmt.Errorf("invalid duration '%s', expected a sequence of <number><unit> (e.g. 1d2h30m)", duration)
+	}
+	if duration == "0" {
+		return 0, nil
+	}
+	var total time.Duration
+	rest := duration
+	for rest != "" {
+		matches := humanDurationRegex.FindStringSubmatch(rest)
+		if matches == nil {
+			return 0, fmt.Errorf("invalid duration '%s', expected a sequence of <number><unit> (e.g. 1d2h30m)", duration)
+		}
+		rest = rest[len(matches[0]):]
+		amount, unit := matches[1], matches[2]
+		var factor time.Duration = 1
+		switch unit {
+		case "d":
+			unit, factor = "h", 24
+		case "w":
+			unit, factor = "h", 24*7
+		}
+		d, err := time.ParseDuration(amount + unit)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s': %w", duration, err)
+		}
+		if d > math.MaxInt64/factor || total > math.MaxInt64-d*factor {
+			return 0, fmt.Errorf("invalid duration '%s': overflow", duration)
+		}
+		total += d * factor
+	}
+	return total, nil
+}
@@ -39,3 +39,27 @@ func TestParseSince(t *testing.T) {
 	yesterday := time.Now().UTC().Add(-24 * time.Hour)
 	assert.Equal(t, yesterday.Minute(), oneDayAgo.Minute())
 }
+
+func TestParseHumanDuration(t *testing.T) {
+	for duration, expected := range map[string]time.Duration{
+		"0":         0,
+		"1d2h30m":   26*time.Hour + 30*time.Minute,
+		"1.5d":      36 * time.Hour,
+		"2w":        14 * 24 * time.Hour,
+		"1h500ms":   time.Hour + 500*time.Millisecond,
+		"90s":       90 * time.Second,
+		"1d1d":      48 * time.Hour,
+		"10us2µs":   12 * time.Microsecond,
+		".5h":       30 * time.Minute,
+		"10000w":    10000 * 7 * 24 * time.Hour,
+		"1m30s15ns": time.Minute + 30*time.Second + 15,
+	} {
+		d, err := ParseHumanDuration(duration)
+		require.NoError(t, err, duration)
+		assert.Equal(t, expected, d, duration)
+	}
+	for _, duration := range []string{"", "1", "d", "1z", "-1h", "1h ", "1 h", "20000w", "10000w10000w"} {
+		_, err := ParseHumanDuration(duration)
+		assert.Error(t, err, duration)
+	}
+}
@@ -0,0 +1,269 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// InstrumentationName is the name of the tracer used by the packages in this repository
+const InstrumentationName = "github.com/argoproj/pkg/v2"
+
+// Environment variables read by ConfigFromEnv. These are the standard OpenTelemetry variables.
+const (
+	EnvEndpoint           = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvTracesEndpoint     = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	EnvProtocol           = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	EnvInsecure           = "OTEL_EXPORTER_OTLP_INSECURE"
+	EnvSampler            = "OTEL_TRACES_SAMPLER"
+	EnvSamplerArg         = "OTEL_TRACES_SAMPLER_ARG"
+	EnvPropagators        = "OTEL_PROPAGATORS"
+	EnvResourceAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+)
+
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// tracesPath is the default URL path of the OTLP/HTTP traces endpoint
+const tracesPath = "/v1/traces"
+
+// Config holds the settings used to configure the tracer provider
+type Config struct {
+	// Endpoint is the OTLP collector endpoint for traces. Tracing is disabled when it is empty. For
+	// http/protobuf, /v1/traces is used as the path if the endpoint has none.
+	Endpoint string
+	// Protocol is either "grpc" or "http/protobuf"
+	Protocol string
+	// Insecure disables TLS when connecting to the collector
+	Insecure bool
+	// SampleRatio is the fraction of root spans which are sampled, between 0 and 1
+	SampleRatio float64
+	// IgnoreParent applies SampleRatio to all spans instead of following the sampling decision of
+	// the parent span
+	IgnoreParent bool
+	// Propagators is the list of context propagators to install (tracecontext, baggage)
+	Propagators []string
+	// ResourceAttributes are additional attributes attached to every span
+	ResourceAttributes map[string]string
+	// ShutdownTimeout bounds how long Shutdown waits for pending spans to be flushed
+	ShutdownTimeout time.Duration
+}
+
+// ConfigFromEnv returns a Config populated from the standard OpenTelemetry environment variables
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Endpoint:           os.Getenv(EnvTracesEndpoint),
+		Protocol:           os.Getenv(EnvProtocol),
+		SampleRatio:        1,
+		Propagators:        []string{"tracecontext", "baggage"},
+		ResourceAttributes: map[string]string{},
+		ShutdownTimeout:    5 * time.Second,
+	}
+	if cfg.Protocol == "" {
+		cfg.Protocol = ProtocolGRPC
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = os.Getenv(EnvEndpoint)
+		// the base endpoint is shared by all signals, over HTTP each signal has its own path below it
+		if cfg.Endpoint != "" && cfg.Protocol == ProtocolHTTPProtobuf {
+			cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/") + tracesPath
+		}
+	}
+	if insecure, err := strconv.ParseBool(os.Getenv(EnvInsecure)); err == nil {
+		cfg.Insecure = insecure
+	}
+	sampler := os.Getenv(EnvSampler)
+	switch sampler {
+	case "always_on", "always_off", "traceidratio":
+		cfg.IgnoreParent = true
+	}
+	switch strings.TrimPrefix(sampler, "parentbased_") {
+	case "always_off":
+		cfg.SampleRatio = 0
+	case "traceidratio":
+		if ratio, err := strconv.ParseFloat(os.Getenv(EnvSamplerArg), 64); err == nil {
+			cfg.SampleRatio = ratio
+		}
+	}
+	if propagators := os.Getenv(EnvPropagators); propagators != "" {
+		cfg.Propagators = splitList(propagators)
+	}
+	for _, kv := range splitList(os.Getenv(EnvResourceAttributes)) {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			cfg.ResourceAttributes[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return cfg
+}
+
+// AddFlags registers command line flags which override the values read from the environment
+func (c *Config) AddFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&c.Endpoint, "otlp-endpoint", c.Endpoint, "OTLP collector endpoint. Tracing is disabled if empty")
+	flags.StringVar(&c.Protocol, "otlp-protocol", c.Protocol, "OTLP protocol, either grpc or http/protobuf")
+	flags.BoolVar(&c.Insecure, "otlp-insecure", c.Insecure, "Disable TLS when connecting to the OTLP collector")
+	flags.Float64Var(&c.SampleRatio, "otlp-sample-ratio", c.SampleRatio, "Fraction of traces to sample, between 0 and 1")
+	flags.StringSliceVar(&c.Propagators, "otlp-propagators", c.Propagators, "Context propagators to install (tracecontext, baggage)")
+	flags.StringToStringVar(&c.ResourceAttributes, "otlp-resource-attributes", c.ResourceAttributes, "Additional resource attributes attached to every span")
+}
+
+// Init configures the global tracer provider and propagators from the environment. The returned
+// function flushes pending spans and must be called before the process exits.
+func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	return InitWithConfig(ctx, serviceName, ConfigFromEnv())
+}
+
+// InitWithConfig configures the global tracer provider and propagators from the supplied config. The
+// returned function flushes pending spans and must be called before the process exits.
+func InitWithConfig(ctx context.Context, serviceName string, cfg Config) (func(context.Context) error, error) {
+	propagator, err := newPropagator(cfg.Propagators)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	if cfg.Endpoint == "" {
+		logging.FromContext(ctx).Debugf("OTLP endpoint not configured, tracing disabled")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	attrs := []resource.Option{
+		resource.WithFromEnv(),
+		resource.WithHost(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+	}
+	for k, v := range cfg.ResourceAttributes {
+		attrs = append(attrs, resource.WithAttributes(attribute.String(k, v)))
+	}
+	res, err := resource.New(ctx, attrs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(newSampler(cfg)),
+	)
+	otel.SetTracerProvider(provider)
+	logging.FromContext(ctx).WithFields(logging.Fields{"endpoint": cfg.Endpoint, "protocol": cfg.Protocol}).Infof("Tracing enabled")
+
+	return func(ctx context.Context) error {
+		if cfg.ShutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.ShutdownTimeout)
+			defer cancel()
+		}
+		return provider.Shutdown(ctx)
+	}, nil
+}
+
+// Tracer returns the tracer used by the packages in this repository
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+func newExporter(ctx context.Context, cfg Config) (*otlptrace.Exporter, error) {
+	switch cfg.Protocol {
+	case ProtocolGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(endpointURL(cfg))}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ProtocolHTTPProtobuf:
+		u, err := url.Parse(endpointURL(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP endpoint '%s': %w", cfg.Endpoint, err)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = tracesPath
+		}
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(u.String())}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol '%s', expected %s or %s", cfg.Protocol, ProtocolGRPC, ProtocolHTTPProtobuf)
+	}
+}
+
+// endpointURL adds a scheme to endpoints which are given as host:port
+func endpointURL(cfg Config) string {
+	if strings.Contains(cfg.Endpoint, "://") {
+		return cfg.Endpoint
+	}
+	if cfg.Insecure {
+		return "http://" + cfg.Endpoint
+	}
+	return "https://" + cfg.Endpoint
+}
+
+func newSampler(cfg Config) sdktrace.Sampler {
+	var sampler sdktrace.Sampler
+	switch {
+	case cfg.SampleRatio >= 1:
+		sampler = sdktrace.AlwaysSample()
+	case cfg.SampleRatio <= 0:
+		sampler = sdktrace.NeverSample()
+	default:
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	if cfg.IgnoreParent {
+		return sampler
+	}
+	return sdktrace.ParentBased(sampler)
+}
+
+func newPropagator(names []string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range names {
+		switch name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "none":
+		default:
+			return nil, fmt.Errorf("unsupported propagator '%s'", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvEndpoint, "collector:4317")
+	t.Setenv(EnvInsecure, "true")
+	t.Setenv(EnvSampler, "parentbased_traceidratio")
+	t.Setenv(EnvSamplerArg, "0.25")
+	t.Setenv(EnvPropagators, "tracecontext")
+	t.Setenv(EnvResourceAttributes, "team=argo, env = prod")
+
+	cfg := ConfigFromEnv()
+	assert.Equal(t, "collector:4317", cfg.Endpoint)
+	assert.Equal(t, ProtocolGRPC, cfg.Protocol)
+	assert.True(t, cfg.Insecure)
+	assert.InDelta(t, 0.25, cfg.SampleRatio, 0.0001)
+	assert.False(t, cfg.IgnoreParent)
+	assert.Equal(t, []string{"tracecontext"}, cfg.Propagators)
+	assert.Equal(t, map[string]string{"team": "argo", "env": "prod"}, cfg.ResourceAttributes)
+}
+
+func TestConfigFromEnvTracesEndpointWins(t *testing.T) {
+	t.Setenv(EnvEndpoint, "collector:4317")
+	t.Setenv(EnvTracesEndpoint, "traces:4317")
+	assert.Equal(t, "traces:4317", ConfigFromEnv().Endpoint)
+}
+
+func TestConfigFromEnvHTTPEndpoint(t *testing.T) {
+	t.Setenv(EnvProtocol, ProtocolHTTPProtobuf)
+	t.Setenv(EnvEndpoint, "http://collector:4318/")
+	assert.Equal(t, "http://collector:4318/v1/traces", ConfigFromEnv().Endpoint)
+	// the traces endpoint is used as is
+	t.Setenv(EnvTracesEndpoint, "http://collector:4318/custom")
+	assert.Equal(t, "http://collector:4318/custom", ConfigFromEnv().Endpoint)
+}
+
+func TestConfigFromEnvSampler(t *testing.T) {
+	for _, tc := range []struct {
+		sampler     string
+		description string
+	}{
+		{"", "ParentBased{root:AlwaysOnSampler,remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}"},
+		{"always_on", "AlwaysOnSampler"},
+		{"always_off", "AlwaysOffSampler"},
+		{"traceidratio", "TraceIDRatioBased{0.5}"},
+		{"parentbased_always_off", "ParentBased{root:AlwaysOffSampler,remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}"},
+		{"parentbased_traceidratio", "ParentBased{root:TraceIDRatioBased{0.5},remoteParentSampled:AlwaysOnSampler,remoteParentNotSampled:AlwaysOffSampler,localParentSampled:AlwaysOnSampler,localParentNotSampled:AlwaysOffSampler}"},
+	} {
+		t.Run(tc.sampler, func(t *testing.T) {
+			t.Setenv(EnvSampler, tc.sampler)
+			t.Setenv(EnvSamplerArg, "0.5")
+			assert.Equal(t, tc.description, newSampler(ConfigFromEnv()).Description())
+		})
+	}
+}
+
+func TestAddFlags(t *testing.T) {
+	cfg := ConfigFromEnv()
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.AddFlags(flags)
+	require.NoError(t, flags.Parse([]string{"--otlp-endpoint=http://localhost:4318", "--otlp-protocol=http/protobuf", "--otlp-sample-ratio=0.5"}))
+	assert.Equal(t, "http://localhost:4318", cfg.Endpoint)
+	assert.Equal(t, ProtocolHTTPProtobuf, cfg.Protocol)
+	assert.InDelta(t, 0.5, cfg.SampleRatio, 0.0001)
+}
+
+func TestInitDisabled(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Endpoint = ""
+	shutdown, err := InitWithConfig(context.Background(), "test", cfg)
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+	assert.NotNil(t, Tracer())
+}
+
+func TestInitErrors(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Propagators = []string{"b3"}
+	_, err := InitWithConfig(context.Background(), "test", cfg)
+	require.Error(t, err)
+
+	cfg = ConfigFromEnv()
+	cfg.Endpoint = "localhost:4317"
+	cfg.Protocol = "thrift"
+	_, err = InitWithConfig(context.Background(), "test", cfg)
+	require.Error(t, err)
+}
+
+func TestEndpointURL(t *testing.T) {
+	assert.Equal(t, "https://collector:4317", endpointURL(Config{Endpoint: "collector:4317"}))
+	assert.Equal(t, "http://collector:4317", endpointURL(Config{Endpoint: "collector:4317", Insecure: true}))
+	assert.Equal(t, "http://collector:4318/v1/traces", endpointURL(Config{Endpoint: "http://collector:4318/v1/traces"}))
+}
@@ -0,0 +1,92 @@
+package warnings
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+// Warning is a non-fatal problem which should be shown to the user separately from errors
+type Warning struct {
+	Message string `json:"message"`
+	// Fields carries optional structured details, e.g. the name of the offending object
+	Fields logging.Fields `json:"fields,omitempty"`
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// Collector accumulates warnings. It is safe for concurrent use. Identical warnings are only
+// recorded once.
+type Collector struct {
+	lock     sync.Mutex
+	warnings []Warning
+	seen     map[string]bool
+}
+
+// NewCollector returns an empty Collector
+func NewCollector() *Collector {
+	return &Collector{seen: map[string]bool{}}
+}
+
+// Add records a warning
+func (c *Collector) Add(w Warning) {
+	key := fmt.Sprintf("%s%v", w.Message, w.Fields)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.seen[key] {
+		return
+	}
+	c.seen[key] = true
+	c.warnings = append(c.warnings, w)
+}
+
+// Addf records a warning with the formatted message
+func (c *Collector) Addf(format string, args ...interface{}) {
+	c.Add(Warning{Message: fmt.Sprintf(format, args...)})
+}
+
+// Warnings returns the recorded warnings in the order they were added
+func (c *Collector) Warnings() []Warning {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]Warning(nil), c.warnings...)
+}
+
+// Len returns the number of recorded warnings
+func (c *Collector) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.warnings)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the collector
+func NewContext(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the collector carried by ctx, or nil
+func FromContext(ctx context.Context) *Collector {
+	c, _ := ctx.Value(contextKey{}).(*Collector)
+	return c
+}
+
+// Add records a warning with the collector carried by ctx. Without a collector the warning is
+// logged instead, so that it is never lost.
+func Add(ctx context.Context, w Warning) {
+	if c := FromContext(ctx); c != nil {
+		c.Add(w)
+		return
+	}
+	logging.FromContext(ctx).WithFields(w.Fields).Warnf("%s", w.Message)
+}
+
+// Addf records a warning with the formatted message with the collector carried by ctx
+func Addf(ctx context.Context, format string, args ...interface{}) {
+	Add(ctx, Warning{Message: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,51 @@
+package warnings
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/argoproj/pkg/v2/logging"
+)
+
+type fakeSink struct {
+	messages []string
+}
+
+func (s *fakeSink) Log(_ logging.Level, msg string, _ logging.Fields) {
+	s.messages = append(s.messages, msg)
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector()
+	ctx := NewContext(context.Background(), c)
+	assert.Same(t, c, FromContext(ctx))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Addf(ctx, "field %q is deprecated", "foo")
+		}()
+	}
+	wg.Wait()
+	Add(ctx, Warning{Message: "ignored invalid label", Fields: logging.Fields{"label": "a/b/c"}})
+
+	assert.Equal(t, 2, c.Len())
+	data, err := json.Marshal(c.Warnings())
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"message":"field \"foo\" is deprecated"},{"message":"ignored invalid label","fields":{"label":"a/b/c"}}]`, string(data))
+}
+
+func TestWithoutCollector(t *testing.T) {
+	sink := &fakeSink{}
+	ctx := logging.NewContext(context.Background(), logging.New(sink, nil))
+	assert.Nil(t, FromContext(ctx))
+	Addf(ctx, "using default %s", "image")
+	assert.Equal(t, []string{"using default image"}, sink.messages)
+}